@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Aggregate 聚合列配置
+type Aggregate struct {
+	Func   string // sum、count、avg、min、max
+	Column string // 聚合的列，count 可使用 "*"
+	Alias  string // 结果中的别名
+}
+
+// 支持的聚合函数
+var aggregateFuncs = map[string]string{
+	"sum":   "SUM",
+	"count": "COUNT",
+	"avg":   "AVG",
+	"min":   "MIN",
+	"max":   "MAX",
+}
+
+// AggregateQuery 通用分组聚合查询，复用 Filter 的筛选条件和可筛选字段限制，按 f.GroupBy 分组
+func AggregateQuery[T any](db *gorm.DB, f *Filter, aggs []Aggregate) ([]map[string]interface{}, error) {
+	if len(aggs) == 0 {
+		return nil, fmt.Errorf("aggregate: no aggregates given")
+	}
+	selects := make([]string, 0, len(f.GroupBy)+len(aggs))
+	groups := make([]string, 0, len(f.GroupBy))
+	for _, col := range f.GroupBy {
+		if !identifierPattern.MatchString(col) || !f.isFilterable(col) {
+			return nil, fmt.Errorf("aggregate: group by column %q is not allowed", col)
+		}
+		groups = append(groups, quoteIdent(db, col))
+	}
+	selects = append(selects, groups...)
+	for _, agg := range aggs {
+		expr, err := f.aggregateExpr(db, agg)
+		if err != nil {
+			return nil, err
+		}
+		selects = append(selects, expr)
+	}
+
+	queryDB, err := f.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return nil, err
+	}
+	queryDB = queryDB.Select(strings.Join(selects, ", "))
+	if len(f.GroupBy) > 0 {
+		// 列已加引号，按原样写入，避免 Group 再次加引号
+		queryDB = queryDB.Clauses(clause.GroupBy{Columns: []clause.Column{{Name: strings.Join(groups, ", "), Raw: true}}})
+		f.recordSQL("GROUP BY", f.GroupBy)
+	}
+	if f.Debug {
+		f.PrintSQLs()
+	}
+
+	var result []map[string]interface{}
+	if err := queryDB.Find(&result).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SumByFilter 按 Filter 的筛选条件和 JOIN 求和（不分页），列需可筛选；没有匹配的记录时返回 Valid 为 false 的零值
+func SumByFilter[T any](db *gorm.DB, f *Filter, column string) (sql.NullFloat64, error) {
+	var res sql.NullFloat64
+	err := aggregateByFilter[T](db, f, "sum", column, &res)
+	return res, err
+}
+
+// AvgByFilter 同 SumByFilter，求平均值
+func AvgByFilter[T any](db *gorm.DB, f *Filter, column string) (sql.NullFloat64, error) {
+	var res sql.NullFloat64
+	err := aggregateByFilter[T](db, f, "avg", column, &res)
+	return res, err
+}
+
+// MinByFilter 同 SumByFilter，求最小值。结果以字符串返回，可用于数字、日期等类型的列
+func MinByFilter[T any](db *gorm.DB, f *Filter, column string) (sql.NullString, error) {
+	var res sql.NullString
+	err := aggregateByFilter[T](db, f, "min", column, &res)
+	return res, err
+}
+
+// MaxByFilter 同 MinByFilter，求最大值
+func MaxByFilter[T any](db *gorm.DB, f *Filter, column string) (sql.NullString, error) {
+	var res sql.NullString
+	err := aggregateByFilter[T](db, f, "max", column, &res)
+	return res, err
+}
+
+// 单个聚合值的查询，结果扫描到 dest
+func aggregateByFilter[T any](db *gorm.DB, f *Filter, fn, column string, dest interface{}) error {
+	expr, err := f.aggregateExpr(db, Aggregate{Func: fn, Column: column, Alias: "result"})
+	if err != nil {
+		return err
+	}
+	queryDB, err := f.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return err
+	}
+	if f.Debug {
+		f.PrintSQLs()
+	}
+	rows, err := queryDB.Select(expr).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		if err := rows.Scan(dest); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// 校验聚合配置并生成 SELECT 表达式，列名和别名按方言加引号
+func (f *Filter) aggregateExpr(db *gorm.DB, agg Aggregate) (string, error) {
+	fn, ok := aggregateFuncs[strings.ToLower(agg.Func)]
+	if !ok {
+		return "", fmt.Errorf("aggregate: unknown function %q", agg.Func)
+	}
+	if !identifierPattern.MatchString(agg.Alias) || strings.Contains(agg.Alias, ".") {
+		return "", fmt.Errorf("aggregate: invalid alias %q", agg.Alias)
+	}
+	column := agg.Column
+	if !(fn == "COUNT" && column == "*") {
+		if !identifierPattern.MatchString(column) || !f.isFilterable(column) {
+			return "", fmt.Errorf("aggregate: column %q is not allowed", column)
+		}
+		column = quoteIdent(db, column)
+	}
+	return fmt.Sprintf("%s(%s) AS %s", fn, column, quoteIdent(db, agg.Alias)), nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestSumByFilterStatementErrorReturnsError(t *testing.T) {
+	db, _ := dryRunDB(t, "sqlite")
+	boom := errors.New("boom")
+	tx := db.Session(&gorm.Session{})
+	_ = tx.AddError(boom)
+	if _, err := MaxByFilter[item](tx, &Filter{}, "stock"); !errors.Is(err, boom) {
+		t.Fatalf("want statement error, got %v", err)
+	}
+}
+
+func TestSumByFilterDryRunReturnsError(t *testing.T) {
+	db, _ := dryRunDB(t, "sqlite")
+	f := &Filter{Filters: map[string]interface{}{"status": 1}}
+	if _, err := SumByFilter[item](db, f, "stock"); !errors.Is(err, gorm.ErrDryRunModeUnsupported) {
+		t.Fatalf("want ErrDryRunModeUnsupported, got %v", err)
+	}
+}
+
+func TestAggregateQueryQuotesColumns(t *testing.T) {
+	db, rec := dryRunDB(t, "mysql")
+	f := &Filter{GroupBy: []string{"name"}}
+	aggs := []Aggregate{{Func: "sum", Column: "stock", Alias: "total"}, {Func: "count", Column: "*", Alias: "n"}}
+	if _, err := AggregateQuery[item](db, f, aggs); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), "SELECT `name`, SUM(`stock`) AS `total`, COUNT(*) AS `n` FROM `items`", "GROUP BY `name`")
+}
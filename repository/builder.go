@@ -0,0 +1,166 @@
+package repository
+
+import "fmt"
+
+// JOIN 类型，用于 FilterBuilder.Join
+const (
+	Inner = "inner"
+	Left  = "left"
+)
+
+// 内置的条件运算符，FilterBuilder.Op 据此校验
+var knownOperators = map[string]bool{
+	"eq": true, "nseq": true, "eq_ci": true, "neq": true, "gt": true, "gte": true, "lt": true, "lte": true,
+	"like": true, "not_like": true, "ilike": true, "regexp": true, "not_regexp": true,
+	"json_contains": true, "overlaps": true, "array_contains": true, "find_in_set": true, "match": true,
+	"date_eq": true, "date_gte": true, "date_lte": true, "range": true,
+	"len_eq": true, "len_gt": true, "len_lt": true, "has_flag": true, "not_has_flag": true,
+	"starts_with": true, "ends_with": true, "contains": true,
+	"in": true, "not_in": true, "is_null": true, "not_null": true, "between": true, "not_between": true,
+}
+
+// FilterBuilder 在 Go 代码中链式构建 Filter，例如：
+//
+//	f, err := NewFilter().Where("age").Gte(18).Where("status").In("a", "b").
+//		Sort("-created_at").Page(2, 20).Join("roles", "users.role_id = roles.id", Left).Build()
+//
+// 链式调用不会 panic，参数错误统一在 Build 时返回
+type FilterBuilder struct {
+	f     Filter
+	field string
+	errs  []FilterError
+}
+
+// NewFilter 创建 FilterBuilder
+func NewFilter() *FilterBuilder {
+	return &FilterBuilder{}
+}
+
+// Where 指定后续运算符作用的字段
+func (b *FilterBuilder) Where(field string) *FilterBuilder {
+	if !identifierPattern.MatchString(jsonColumn(field)) {
+		b.errs = append(b.errs, FilterError{Field: field, Reason: "invalid field name"})
+	}
+	b.field = field
+	return b
+}
+
+// Op 为当前字段添加条件，同一字段的多个运算符以 AND 连接
+func (b *FilterBuilder) Op(op string, value interface{}) *FilterBuilder {
+	switch {
+	case b.field == "":
+		b.errs = append(b.errs, FilterError{Op: op, Reason: "missing Where before operator"})
+		return b
+	case !knownOperators[op]:
+		b.errs = append(b.errs, FilterError{Field: b.field, Op: op, Reason: "unknown operator"})
+		return b
+	}
+	if b.f.Filters == nil {
+		b.f.Filters = make(map[string]interface{})
+	}
+	conds, _ := b.f.Filters[b.field].(map[string]interface{})
+	if conds == nil {
+		conds = make(map[string]interface{})
+		b.f.Filters[b.field] = conds
+	}
+	if _, ok := conds[op]; ok {
+		b.errs = append(b.errs, FilterError{Field: b.field, Op: op, Reason: "duplicate operator"})
+		return b
+	}
+	conds[op] = value
+	return b
+}
+
+// Eq 等于
+func (b *FilterBuilder) Eq(value interface{}) *FilterBuilder { return b.Op("eq", value) }
+
+// Neq 不等于
+func (b *FilterBuilder) Neq(value interface{}) *FilterBuilder { return b.Op("neq", value) }
+
+// Gt 大于
+func (b *FilterBuilder) Gt(value interface{}) *FilterBuilder { return b.Op("gt", value) }
+
+// Gte 大于等于
+func (b *FilterBuilder) Gte(value interface{}) *FilterBuilder { return b.Op("gte", value) }
+
+// Lt 小于
+func (b *FilterBuilder) Lt(value interface{}) *FilterBuilder { return b.Op("lt", value) }
+
+// Lte 小于等于
+func (b *FilterBuilder) Lte(value interface{}) *FilterBuilder { return b.Op("lte", value) }
+
+// Like 模糊匹配，value 需自带 % 通配符
+func (b *FilterBuilder) Like(value string) *FilterBuilder { return b.Op("like", value) }
+
+// Contains 包含，特殊字符自动转义
+func (b *FilterBuilder) Contains(value string) *FilterBuilder { return b.Op("contains", value) }
+
+// In 属于列表
+func (b *FilterBuilder) In(values ...interface{}) *FilterBuilder { return b.Op("in", values) }
+
+// NotIn 不属于列表
+func (b *FilterBuilder) NotIn(values ...interface{}) *FilterBuilder { return b.Op("not_in", values) }
+
+// Between 闭区间
+func (b *FilterBuilder) Between(from, to interface{}) *FilterBuilder {
+	return b.Op("between", []interface{}{from, to})
+}
+
+// IsNull 为 NULL
+func (b *FilterBuilder) IsNull() *FilterBuilder { return b.Op("is_null", true) }
+
+// NotNull 不为 NULL
+func (b *FilterBuilder) NotNull() *FilterBuilder { return b.Op("not_null", true) }
+
+// Filterable 设置可供筛选的字段
+func (b *FilterBuilder) Filterable(fields ...string) *FilterBuilder {
+	b.f.Filterable = fields
+	return b
+}
+
+// Sortable 设置可供排序的字段
+func (b *FilterBuilder) Sortable(fields ...string) *FilterBuilder {
+	b.f.Sortable = fields
+	return b
+}
+
+// Sort 设置排序，写法同 Filter.Sort
+func (b *FilterBuilder) Sort(sort string) *FilterBuilder {
+	b.f.Sort = sort
+	return b
+}
+
+// Page 设置页码和每页条数
+func (b *FilterBuilder) Page(page, pageSize int) *FilterBuilder {
+	if page < 0 || pageSize < 0 {
+		b.errs = append(b.errs, FilterError{Field: "page", Reason: fmt.Sprintf("invalid page %d, page size %d", page, pageSize)})
+		return b
+	}
+	b.f.Page = page
+	b.f.PageSize = pageSize
+	return b
+}
+
+// Join 添加 JOIN，joinType 为 Inner 或 Left
+func (b *FilterBuilder) Join(table, on, joinType string) *FilterBuilder {
+	if joinType != Inner && joinType != Left {
+		b.errs = append(b.errs, FilterError{Field: table, Op: "join", Reason: fmt.Sprintf("unknown join type %q", joinType)})
+		return b
+	}
+	b.f.Joins = append(b.f.Joins, JoinConfig{Table: table, On: on, JoinType: joinType})
+	return b
+}
+
+// Unscoped 包含软删除的记录
+func (b *FilterBuilder) Unscoped() *FilterBuilder {
+	b.f.Unscoped = true
+	return b
+}
+
+// Build 返回构建的 Filter，链式调用中的参数错误在此一并返回
+func (b *FilterBuilder) Build() (*Filter, error) {
+	if len(b.errs) > 0 {
+		return nil, joinFilterErrors(b.errs)
+	}
+	return b.f.Clone(), nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterBuilderMatchesFilter(t *testing.T) {
+	built, err := NewFilter().
+		Where("status").In(1, 2).
+		Filterable("status").Sortable("name").Sort("-name").Page(2, 20).
+		Join("roles", "roles.id = items.status", Left).Unscoped().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manual := &Filter{
+		Filters:    map[string]interface{}{"status": map[string]interface{}{"in": []interface{}{1, 2}}},
+		Filterable: []string{"status"},
+		Sortable:   []string{"name"},
+		Sort:       "-name",
+		Page:       2,
+		PageSize:   20,
+		Joins:      []JoinConfig{{Table: "roles", On: "roles.id = items.status", JoinType: "left"}},
+		Unscoped:   true,
+	}
+	if !reflect.DeepEqual(built.Filters, manual.Filters) {
+		t.Errorf("filters = %#v, want %#v", built.Filters, manual.Filters)
+	}
+
+	db, rec := dryRunDB(t, "mysql")
+	want, err := findSQL(t, db, rec, manual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := findSQL(t, db, rec, built)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("builder sql:\n%s\nfilter sql:\n%s", got, want)
+	}
+	assertContains(t, got, "LEFT JOIN `roles`", "`status` IN (1,2)", "ORDER BY `name` DESC", "LIMIT 20 OFFSET 20")
+}
+
+func TestFilterBuilderErrors(t *testing.T) {
+	if _, err := NewFilter().Eq(1).Build(); err == nil {
+		t.Error("want error for operator without Where")
+	}
+	if _, err := NewFilter().Where("name").Op("bogus", 1).Build(); err == nil {
+		t.Error("want error for unknown operator")
+	}
+	if _, err := NewFilter().Join("roles", "roles.id = items.status", "cross").Build(); err == nil {
+		t.Error("want error for unknown join type")
+	}
+}
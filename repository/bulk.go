@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdateWithFilter 按 Filter 的筛选条件批量更新（不排序、不分页），返回影响的行数。
+// 筛选条件按严格模式校验，任一条件被拒绝时返回错误且不执行；Filter 没有产生任何条件时返回 ErrNoConditions，确需更新整张表时 allowFullTable 传 true；
+// 带 JOIN 的 Filter 改写为 id IN (子查询)
+func UpdateWithFilter[T any](db *gorm.DB, f *Filter, updates map[string]interface{}, allowFullTable bool) (int64, error) {
+	if len(updates) == 0 {
+		return 0, ErrEmptyUpdates
+	}
+	return execWithFilter[T](db, f, allowFullTable, func(tx *gorm.DB) *gorm.DB {
+		return tx.Updates(updates)
+	})
+}
+
+// DeleteWithFilter 按 Filter 的筛选条件批量设置 is_deleted = 1，返回影响的行数，条件为空时的处理同 UpdateWithFilter
+func DeleteWithFilter[T any](db *gorm.DB, f *Filter, allowFullTable bool) (int64, error) {
+	return execWithFilter[T](db, f, allowFullTable, func(tx *gorm.DB) *gorm.DB {
+		return tx.UpdateColumn("is_deleted", 1)
+	})
+}
+
+// SoftDeleteWithFilter 按 Filter 的筛选条件批量软删除，模型需有 gorm.DeletedAt 字段，条件为空时的处理同 UpdateWithFilter
+func SoftDeleteWithFilter[T any](db *gorm.DB, f *Filter, allowFullTable bool) (int64, error) {
+	return execWithFilter[T](db, f, allowFullTable, func(tx *gorm.DB) *gorm.DB {
+		return tx.Delete(new(T))
+	})
+}
+
+// 在 Filter 选中的记录上执行 op。调试模式下执行前打印条件和将要执行的 SQL
+func execWithFilter[T any](db *gorm.DB, f *Filter, allowFullTable bool, op func(tx *gorm.DB) *gorm.DB) (int64, error) {
+	target, c, err := filterTarget[T](db, f, allowFullTable)
+	if err != nil {
+		if c.Debug {
+			c.PrintSQLs()
+		}
+		return 0, err
+	}
+	if c.Debug {
+		c.finalSQL = target.ToSQL(op)
+		c.PrintSQLs()
+	}
+	result := op(target)
+	return result.RowsAffected, result.Error
+}
+
+// 批量更新、删除的目标：只应用 Filter 的筛选条件，忽略查询列、去重、排序和分页。
+// 始终按严格模式校验，不可筛选的字段、按 IgnoreZero 跳过的值都返回错误，避免少一个条件扩大影响范围
+func filterTarget[T any](db *gorm.DB, f *Filter, allowFullTable bool) (*gorm.DB, *Filter, error) {
+	c := f.Clone()
+	c.Fields, c.OmitFields, c.Distinct, c.DistinctColumns = nil, nil, false, nil
+	c.Strict, c.rejectIgnored = true, true
+	queryDB, err := c.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return nil, c, err
+	}
+	if !hasConditions(queryDB) {
+		if !allowFullTable {
+			return nil, c, ErrNoConditions
+		}
+		// gorm 默认拒绝没有条件的更新、删除
+		queryDB = queryDB.Session(&gorm.Session{AllowGlobalUpdate: true})
+	}
+	if len(c.Joins) == 0 {
+		return queryDB, c, nil
+	}
+	// UPDATE / DELETE 不支持 JOIN，改为按主键匹配；多包一层派生表，MySQL 不允许子查询直接读取被更新的表
+	target := db.Model(new(T))
+	if c.Unscoped {
+		target = target.Unscoped()
+	}
+	sub := queryDB.Select(quoteIdent(queryDB, c.keysetColumn()))
+	return target.Where(fmt.Sprintf("%s IN (SELECT id FROM (?) AS filtered)", quoteIdent(target, "id")), sub), c, nil
+}
+
+// 语句中是否有 WHERE 条件
+func hasConditions(db *gorm.DB) bool {
+	c, ok := db.Statement.Clauses["WHERE"]
+	if !ok {
+		return false
+	}
+	where, ok := c.Expression.(clause.Where)
+	return ok && len(where.Exprs) > 0
+}
+
+// DefaultBulkUpdateChunkSize BulkUpdateColumn 每条语句更新的最大行数，仓储可通过 WithBulkUpdateChunkSize 修改
+const DefaultBulkUpdateChunkSize = 500
+
+// BulkUpdateColumn 按 id 把 column 分别更新为 values 中对应的值，生成
+// UPDATE ... SET column = CASE id WHEN ? THEN ? ... ELSE column END WHERE id IN (...)，
+// 超过 DefaultBulkUpdateChunkSize 的行数时分多条语句执行，返回各语句影响的行数之和。
+// 未开启 SkipDefaultTransaction 时多条语句在同一事务中执行；values 为空时不执行任何语句
+func BulkUpdateColumn[T any](db *gorm.DB, column string, values map[uint]interface{}) (int64, error) {
+	return bulkUpdateColumn[T](db, column, values, DefaultBulkUpdateChunkSize)
+}
+
+// chunkSize <= 0 时使用 DefaultBulkUpdateChunkSize
+func bulkUpdateColumn[T any](db *gorm.DB, column string, values map[uint]interface{}, chunkSize int) (int64, error) {
+	if !identifierPattern.MatchString(column) {
+		return 0, fmt.Errorf("invalid column %q", column)
+	}
+	if len(values) == 0 {
+		return 0, nil
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultBulkUpdateChunkSize
+	}
+	ids := make([]uint, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	col := quoteIdent(db, column)
+	var rows int64
+	update := func(tx *gorm.DB) error {
+		for start := 0; start < len(ids); start += chunkSize {
+			chunk := ids[start:min(start+chunkSize, len(ids))]
+			var sql strings.Builder
+			args := make([]interface{}, 0, len(chunk)*2)
+			sql.WriteString("CASE id")
+			for _, id := range chunk {
+				sql.WriteString(" WHEN ? THEN ?")
+				args = append(args, id, values[id])
+			}
+			// ELSE 分支让 Postgres 按列的类型推断参数类型
+			sql.WriteString(" ELSE " + col + " END")
+			result := tx.Model(new(T)).
+				Where("id IN ?", chunk).
+				UpdateColumn(column, gorm.Expr(sql.String(), args...))
+			if result.Error != nil {
+				return fmt.Errorf("bulk update: chunk %d failed: %w", start/chunkSize+1, result.Error)
+			}
+			rows += result.RowsAffected
+		}
+		return nil
+	}
+	var err error
+	if db.SkipDefaultTransaction || len(ids) <= chunkSize {
+		err = update(db)
+	} else {
+		err = db.Transaction(update)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUpdateWithFilterRejectsDroppedCondition(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{Filterable: []string{"name"}, QueryStr: `{"name":"a","bogus_col":1}`}
+	_, err := UpdateWithFilter[item](db, f, map[string]interface{}{"name": "y"}, false)
+	var fe FilterError
+	if !errors.As(err, &fe) || fe.Field != "bogus_col" {
+		t.Fatalf("want filter error on bogus_col, got %v", err)
+	}
+	if sql := rec.last(); sql != "" {
+		t.Errorf("update should not run, got %s", sql)
+	}
+}
+
+func TestUpdateWithFilterRejectsIgnoredZero(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{IgnoreZero: true, Filters: map[string]interface{}{"name": "a", "status": ""}}
+	if _, err := UpdateWithFilter[item](db, f, map[string]interface{}{"stock": 0}, false); err == nil {
+		t.Fatal("want error for ignored empty value")
+	}
+	if sql := rec.last(); sql != "" {
+		t.Errorf("update should not run, got %s", sql)
+	}
+}
+
+func TestUpdateWithFilter(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{Filterable: []string{"name"}, QueryStr: `{"name":"a"}`}
+	if _, err := UpdateWithFilter[item](db, f, map[string]interface{}{"stock": 1}, false); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), `UPDATE "items" SET`, `"stock"=1`, `"name" = 'a'`)
+	if f.Strict {
+		t.Error("caller's filter should stay non-strict")
+	}
+}
+
+func TestUpdateWithFilterNoConditions(t *testing.T) {
+	db, _ := dryRunDB(t, "sqlite")
+	if _, err := UpdateWithFilter[item](db, &Filter{}, map[string]interface{}{"stock": 1}, false); !errors.Is(err, ErrNoConditions) {
+		t.Fatalf("want ErrNoConditions, got %v", err)
+	}
+}
+
+func TestDeleteWithFilterRejectsPartiallyInvalidFilter(t *testing.T) {
+	db, rec := dryRunDB(t, "mysql")
+	f := &Filter{Filterable: []string{"name"}, QueryStr: `{"name":"a","bogus_col":1}`}
+	if _, err := DeleteWithFilter[item](db, f, false); err == nil {
+		t.Fatal("want error for non-filterable field")
+	}
+	if _, err := SoftDeleteWithFilter[item](db, f, false); err == nil {
+		t.Fatal("want error for non-filterable field")
+	}
+	if sqls := rec.all(); len(sqls) != 0 {
+		t.Errorf("delete should not run, got %v", sqls)
+	}
+}
+
+func TestSoftDeleteWithFilter(t *testing.T) {
+	db, rec := dryRunDB(t, "mysql")
+	f := &Filter{Filterable: []string{"name"}, QueryStr: `{"name":"a"}`}
+	if _, err := SoftDeleteWithFilter[item](db, f, false); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), "UPDATE `items` SET `deleted_at`=", "`name` = 'a'")
+}
@@ -0,0 +1,106 @@
+package repository
+
+// Clone 深拷贝 Filter，条件 map、切片、Joins 等都不与原 Filter 共用，可在副本上修改分页、排序等派生查询。
+// 调试记录、finalSQL、错误等内部状态不会复制，副本从零值开始；Location、Now 等只读配置仍共用
+func (f *Filter) Clone() *Filter {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	c.resetState()
+
+	c.Filterable = cloneStrings(f.Filterable)
+	c.Sortable = cloneStrings(f.Sortable)
+	c.AlwaysSortable = cloneStrings(f.AlwaysSortable)
+	c.Filters = cloneConditions(f.Filters)
+	c.BaseConditions = cloneConditions(f.BaseConditions)
+	c.Joins = append([]JoinConfig(nil), f.Joins...)
+	c.FieldTypes = cloneStringMap(f.FieldTypes)
+	c.FieldAliases = cloneStringMap(f.FieldAliases)
+	c.exprs = cloneStringMap(f.exprs)
+	c.SortExprs = cloneStringMap(f.SortExprs)
+	c.SortAliases = cloneStringMap(f.SortAliases)
+
+	if f.RawConditions != nil {
+		c.RawConditions = make([]RawCondition, len(f.RawConditions))
+		for i, rc := range f.RawConditions {
+			c.RawConditions[i] = RawCondition{SQL: rc.SQL, Args: append([]interface{}(nil), rc.Args...)}
+		}
+	}
+	if f.Exists != nil {
+		c.Exists = make([]ExistsCondition, len(f.Exists))
+		for i, ec := range f.Exists {
+			ec.Conditions = cloneConditions(ec.Conditions)
+			ec.Filterable = cloneStrings(ec.Filterable)
+			c.Exists[i] = ec
+		}
+	}
+	if f.InSubqueries != nil {
+		c.InSubqueries = make([]InSubquery, len(f.InSubqueries))
+		for i, sq := range f.InSubqueries {
+			sq.Conditions = cloneConditions(sq.Conditions)
+			sq.Filterable = cloneStrings(sq.Filterable)
+			c.InSubqueries[i] = sq
+		}
+	}
+
+	c.DistinctColumns = cloneStrings(f.DistinctColumns)
+	c.Fields = cloneStrings(f.Fields)
+	c.OmitFields = cloneStrings(f.OmitFields)
+	c.Selectable = cloneStrings(f.Selectable)
+	c.GroupBy = cloneStrings(f.GroupBy)
+	c.SearchFields = cloneStrings(f.SearchFields)
+	return &c
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+// 递归拷贝条件 map 及其中的 map、数组
+func cloneConditions(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	res := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		res[k] = cloneValue(v)
+	}
+	return res
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return cloneConditions(val)
+	case []map[string]interface{}:
+		res := make([]map[string]interface{}, len(val))
+		for i, m := range val {
+			res[i] = cloneConditions(m)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(val))
+		for i, item := range val {
+			res[i] = cloneValue(item)
+		}
+		return res
+	case []string:
+		return cloneStrings(val)
+	}
+	return v
+}
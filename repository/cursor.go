@@ -0,0 +1,378 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor 游标无法解析、签名不符或与当前排序不一致，可通过 errors.Is 判断
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+var (
+	cursorSecretMu sync.RWMutex
+	cursorSecret   = randomSecret()
+)
+
+func randomSecret() []byte {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return b
+}
+
+// SetCursorSecret 设置游标的签名密钥。未设置时使用进程启动时生成的随机密钥，
+// 多实例部署或需要游标在重启后仍然有效时，需在各实例设置相同的密钥
+func SetCursorSecret(secret []byte) {
+	cursorSecretMu.Lock()
+	defer cursorSecretMu.Unlock()
+	cursorSecret = append([]byte(nil), secret...)
+}
+
+// 游标内容：排序、本页边界记录的排序列值、翻页方向
+type cursorPayload struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+	Prev   bool          `json:"p,omitempty"`
+}
+
+// PrevCursor 最近一次 QueryWithCursor 返回页的上一页游标，已是第一页时为空
+func (f *Filter) PrevCursor() string {
+	return f.prevCursor
+}
+
+// QueryWithCursor 按不透明游标分页查询，不统计总数。f.Cursor 为空时查询第一页，
+// 返回下一页的游标，没有更多数据时为空；上一页的游标通过 f.PrevCursor() 获取。
+// 游标带签名并记录了排序，被篡改或与当前 Sort 不一致时返回 ErrInvalidCursor。
+// 排序缺少 id 时自动追加 id（方向与最后一个排序项相同），保证翻页不重复不遗漏；不支持 nulls、ci 修饰和 SortExprs，
+// 排序列不能为 NULL，模型中可为 NULL 的列（指针、sql.NullString 等类型且没有 not null 标签）返回错误。
+// 多列排序时定位条件覆盖全部排序列，切换排序后旧游标失效
+func QueryWithCursor[T any](db *gorm.DB, f *Filter) ([]T, string, error) {
+	f.prevCursor = ""
+	if f.Page > 1 {
+		return nil, "", FilterError{Field: "page", Reason: "offset pagination cannot be combined with cursor"}
+	}
+	terms, sort, err := f.cursorSortTerms()
+	if err != nil {
+		return nil, "", err
+	}
+	var cur cursorPayload
+	if f.Cursor != "" {
+		if cur, err = decodeCursor(f.Cursor); err != nil {
+			return nil, "", err
+		}
+		if cur.Sort != sort || len(cur.Values) != len(terms) {
+			return nil, "", fmt.Errorf("%w: cursor was created for sort %q, current sort is %q", ErrInvalidCursor, cur.Sort, sort)
+		}
+	}
+
+	// 向前翻页时反转排序方向，查询后再把结果倒回来
+	queryTerms := terms
+	if cur.Prev {
+		queryTerms = make([]sortTerm, len(terms))
+		for i, t := range terms {
+			t.order = reverseOrder(t.order)
+			queryTerms[i] = t
+		}
+	}
+	sortStr := f.Sort
+	f.Sort = formatSortTerms(queryTerms)
+	defer func() { f.Sort = sortStr }()
+
+	queryDB, err := f.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return nil, "", err
+	}
+	if err := f.checkCursorNullable(terms); err != nil {
+		return nil, "", err
+	}
+	if f.Cursor != "" {
+		if queryDB, err = f.applySeek(queryDB, queryTerms, cur.Values); err != nil {
+			return nil, "", err
+		}
+	}
+	if queryDB, err = f.ApplySortAndPaginationE(queryDB); err != nil {
+		return nil, "", err
+	}
+	// 多取一条判断是否还有数据
+	queryDB = queryDB.Limit(f.PageSize + 1)
+	if f.Debug {
+		f.PrintSQLs()
+	}
+	var result []T
+	if err := queryDB.Find(&result).Error; err != nil {
+		return nil, "", err
+	}
+	hasMore := len(result) > f.PageSize
+	if hasMore {
+		result = result[:f.PageSize]
+	}
+	if cur.Prev {
+		for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+			result[i], result[j] = result[j], result[i]
+		}
+	}
+	if len(result) == 0 {
+		return result, "", nil
+	}
+
+	var next string
+	if hasMore || cur.Prev {
+		if next, err = f.encodeRecordCursor(terms, sort, reflect.ValueOf(&result[len(result)-1]).Elem(), false); err != nil {
+			return nil, "", err
+		}
+	}
+	if (hasMore && cur.Prev) || (!cur.Prev && f.Cursor != "") {
+		if f.prevCursor, err = f.encodeRecordCursor(terms, sort, reflect.ValueOf(&result[0]).Elem(), true); err != nil {
+			return nil, "", err
+		}
+	}
+	return result, next, nil
+}
+
+// 解析并校验游标分页的排序，缺少 id 时追加 id 作为唯一的排序依据
+func (f *Filter) cursorSortTerms() ([]sortTerm, string, error) {
+	var terms []sortTerm
+	hasID := false
+	seen := make(map[string]bool)
+	for _, s := range strings.Split(f.Sort, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		t, err := parseSortTerm(s)
+		if err != nil {
+			return nil, "", FilterError{Field: s, Op: "sort", Reason: err.Error()}
+		}
+		if seen[t.field] {
+			continue
+		}
+		seen[t.field] = true
+		if t.nulls != "" {
+			return nil, "", FilterError{Field: t.field, Op: "sort", Reason: "nulls ordering is not supported with cursor"}
+		}
+		if t.ci {
+			return nil, "", FilterError{Field: t.field, Op: "sort", Reason: "case-insensitive ordering is not supported with cursor"}
+		}
+		if _, ok := f.SortExprs[t.field]; ok {
+			return nil, "", FilterError{Field: t.field, Op: "sort", Reason: "sort expressions are not supported with cursor"}
+		}
+		field := f.resolveSortAlias(t.field)
+		sortable := f.isSortable(field)
+		if _, ok := f.SortAliases[t.field]; ok {
+			// 排序别名按对外名称校验
+			sortable = f.isSortable(t.field)
+		}
+		if !identifierPattern.MatchString(field) || !sortable {
+			return nil, "", FilterError{Field: t.field, Op: "sort", Reason: f.notSortableReason()}
+		}
+		if field == "id" {
+			hasID = true
+		}
+		terms = append(terms, t)
+	}
+	if !hasID {
+		// 方向与最后一个排序项相同，便于 Postgres 使用行比较
+		order := "ASC"
+		if len(terms) > 0 {
+			order = terms[len(terms)-1].order
+		}
+		terms = append(terms, sortTerm{field: "id", order: order})
+	}
+	return terms, formatSortTerms(terms), nil
+}
+
+// 游标的排序列不能为 NULL：模型中类型可为 NULL（指针、sql.NullString、gorm.DeletedAt 等）且没有 not null 标签的列返回错误。
+// JOIN 表的列无法从模型判断，需由调用方保证不为 NULL
+func (f *Filter) checkCursorNullable(terms []sortTerm) error {
+	if f.schema == nil {
+		return nil
+	}
+	for _, t := range terms {
+		col := f.resolveSortAlias(t.field)
+		if table, name, ok := strings.Cut(col, "."); ok {
+			if table != f.schema.Table {
+				continue
+			}
+			col = name
+		}
+		field := f.schema.LookUpField(col)
+		if field == nil || field.NotNull || field.PrimaryKey {
+			continue
+		}
+		if nullableType(field.FieldType) {
+			return FilterError{Field: t.field, Op: "sort", Reason: "nullable column is not supported with cursor"}
+		}
+	}
+	return nil
+}
+
+// 类型能否表示 NULL：指针，或带 Valid 字段的结构体（sql.NullString、gorm.DeletedAt 等）
+func nullableType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	valid, ok := t.FieldByName("Valid")
+	return ok && valid.Type.Kind() == reflect.Bool
+}
+
+// 生成定位条件，如排序 a, -b 时为 (a > ?) OR (a = ? AND b < ?)，Postgres 上排序 -a, -b 时为 (a, b) < (?, ?)
+func (f *Filter) applySeek(db *gorm.DB, terms []sortTerm, values []interface{}) (*gorm.DB, error) {
+	columns := make([]string, len(terms))
+	vals := make([]interface{}, len(terms))
+	for i, t := range terms {
+		field := f.resolveSortAlias(t.field)
+		// col > NULL 永远不成立，翻页会在 NULL 处静默结束
+		if values[i] == nil {
+			return db, fmt.Errorf("%w: sort field %q has a null value", ErrInvalidCursor, t.field)
+		}
+		v, err := f.coerceValue(field, values[i])
+		if err != nil {
+			return db, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		columns[i] = quoteIdent(db, f.sortColumn(field))
+		vals[i] = v
+	}
+	// Postgres 上各列方向一致时使用行比较 (a, b) > (?, ?)，可以利用联合索引
+	if dialectName(db) == "postgres" && sameOrder(terms) {
+		op := ">"
+		if terms[0].order == "DESC" {
+			op = "<"
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(vals)), ", ")
+		f.recordSQL("SEEK", vals)
+		return db.Where(fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), op, placeholders), vals...), nil
+	}
+	// 其余情况展开为 OR 条件链
+	var (
+		clauses []string
+		args    []interface{}
+	)
+	for i, t := range terms {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, columns[j]+" = ?")
+			args = append(args, vals[j])
+		}
+		op := ">"
+		if t.order == "DESC" {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", columns[i], op))
+		args = append(args, vals[i])
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	f.recordSQL("SEEK", vals)
+	return db.Where(strings.Join(clauses, " OR "), args...), nil
+}
+
+// 按记录的排序列值生成游标
+func (f *Filter) encodeRecordCursor(terms []sortTerm, sort string, v reflect.Value, prev bool) (string, error) {
+	if f.schema == nil {
+		return "", errors.New("cursor requires a model")
+	}
+	values := make([]interface{}, len(terms))
+	for i, t := range terms {
+		col := f.resolveSortAlias(t.field)
+		if table, name, ok := strings.Cut(col, "."); ok && table == f.schema.Table {
+			col = name
+		}
+		field := f.schema.LookUpField(col)
+		if field == nil {
+			return "", fmt.Errorf("cursor sort field %q is not a column of %s", t.field, f.schema.Name)
+		}
+		values[i], _ = field.ValueOf(context.Background(), v)
+	}
+	return encodeCursor(cursorPayload{Sort: sort, Values: values, Prev: prev})
+}
+
+// 各排序项方向是否一致
+func sameOrder(terms []sortTerm) bool {
+	for _, t := range terms {
+		if t.order != terms[0].order {
+			return false
+		}
+	}
+	return true
+}
+
+func formatSortTerms(terms []sortTerm) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t.field
+		if t.order == "DESC" {
+			parts[i] = "-" + t.field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func reverseOrder(order string) string {
+	if order == "DESC" {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+// 游标格式：base64(JSON 内容).base64(HMAC-SHA256 签名)
+func encodeCursor(p cursorPayload) (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signCursor(payload)), nil
+}
+
+func decodeCursor(token string) (cursorPayload, error) {
+	var p cursorPayload
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return p, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, signCursor(payload)) {
+		return p, fmt.Errorf("%w: bad signature", ErrInvalidCursor)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return p, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+	// 使用 json.Number 解码，避免大整数丢失精度
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&p); err != nil {
+		return p, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+	for i, v := range p.Values {
+		if n, ok := v.(json.Number); ok {
+			if iv, err := n.Int64(); err == nil {
+				p.Values[i] = iv
+			} else if fv, err := n.Float64(); err == nil {
+				p.Values[i] = fv
+			}
+		}
+	}
+	return p, nil
+}
+
+func signCursor(payload string) []byte {
+	cursorSecretMu.RLock()
+	defer cursorSecretMu.RUnlock()
+	h := hmac.New(sha256.New, cursorSecret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type article struct {
+	ID          uint
+	Title       string
+	PublishedAt *time.Time
+	ReviewedAt  *time.Time `gorm:"not null"`
+}
+
+func TestCursorRejectsNullableColumn(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{Sortable: []string{"published_at"}, Sort: "-published_at"}
+	_, _, err := QueryWithCursor[article](db, f)
+	var fe FilterError
+	if !errors.As(err, &fe) || fe.Field != "published_at" {
+		t.Fatalf("want filter error on published_at, got %v", err)
+	}
+	if sql := rec.last(); sql != "" {
+		t.Errorf("query should not run, got %s", sql)
+	}
+
+	f = &Filter{Sortable: []string{"reviewed_at", "title"}, Sort: "-reviewed_at,title"}
+	if _, _, err := QueryWithCursor[article](db, f); err != nil {
+		t.Errorf("not null columns should be allowed: %v", err)
+	}
+}
+
+func TestCursorNullValue(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	cursor, err := encodeCursor(cursorPayload{Sort: "title,id", Values: []interface{}{nil, int64(5)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &Filter{Sortable: []string{"title"}, Sort: "title", Cursor: cursor}
+	if _, _, err := QueryWithCursor[article](db, f); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("want ErrInvalidCursor, got %v", err)
+	}
+	if sql := rec.last(); sql != "" {
+		t.Errorf("query should not run, got %s", sql)
+	}
+}
+
+func TestCursorQualifiesIDWithJoins(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	cursor, err := encodeCursor(cursorPayload{Sort: "name,id", Values: []interface{}{"a", int64(5)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &Filter{
+		Joins:    []JoinConfig{{Table: "roles", On: "roles.id = items.status"}},
+		Sortable: []string{"name"},
+		Sort:     "name",
+		Cursor:   cursor,
+	}
+	if _, _, err := QueryWithCursor[item](db, f); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(),
+		`("name" > 'a') OR ("name" = 'a' AND "items"."id" > 5)`,
+		`ORDER BY "name" ASC,"items"."id" ASC`)
+}
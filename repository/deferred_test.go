@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 预置数据的模拟表：普通查询需读取 OFFSET 跳过的整行，只查询主键时只读取主键（相当于覆盖索引），
+// 按主键 IN 查询时只读取命中的行
+type tableDriver struct {
+	ids      []int64
+	payloads []string
+}
+
+func newTableDriver(rows, width int) *tableDriver {
+	d := &tableDriver{ids: make([]int64, rows), payloads: make([]string, rows)}
+	for i := range d.ids {
+		d.ids[i] = int64(i + 1)
+		d.payloads[i] = strings.Repeat(strconv.Itoa(i%10), width)
+	}
+	return d
+}
+
+func (d *tableDriver) Open(string) (driver.Conn, error) { return tableConn{d}, nil }
+
+type tableConn struct {
+	d *tableDriver
+}
+
+func (c tableConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c tableConn) Close() error                        { return nil }
+func (c tableConn) Begin() (driver.Tx, error)           { return slowTx{}, nil }
+
+var (
+	limitPattern  = regexp.MustCompile(`LIMIT \?( OFFSET \?)?$`)
+	idOnlyPattern = regexp.MustCompile(`^SELECT "items"\."id" FROM`)
+)
+
+func (c tableConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if i := strings.Index(query, `"items"."id" IN (`); i >= 0 {
+		rows := &slowRows{cols: []string{"id", "name"}}
+		// 跳过 IN 之前的条件参数
+		for _, arg := range args[strings.Count(query[:i], "?"):] {
+			if id, ok := arg.Value.(int64); ok && id >= 1 && int(id) <= len(c.d.ids) {
+				rows.values = append(rows.values, []driver.Value{id, copyString(c.d.payloads[id-1])})
+			}
+		}
+		return rows, nil
+	}
+	limit, offset := len(c.d.ids), 0
+	if m := limitPattern.FindStringSubmatch(query); m != nil {
+		// LIMIT、OFFSET 为最后的参数
+		n := len(args)
+		if m[1] != "" {
+			limit, offset = int(args[n-2].Value.(int64)), int(args[n-1].Value.(int64))
+		} else {
+			limit = int(args[n-1].Value.(int64))
+		}
+	}
+	end := offset + limit
+	if end > len(c.d.ids) {
+		end = len(c.d.ids)
+	}
+	if idOnlyPattern.MatchString(query) {
+		rows := &slowRows{cols: []string{"id"}}
+		for i := offset; i < end; i++ {
+			rows.values = append(rows.values, []driver.Value{c.d.ids[i]})
+		}
+		return rows, nil
+	}
+	rows := &slowRows{cols: []string{"id", "name"}}
+	for i := 0; i < end; i++ {
+		// 跳过的行同样需要读取
+		payload := copyString(c.d.payloads[i])
+		if i >= offset {
+			rows.values = append(rows.values, []driver.Value{c.d.ids[i], payload})
+		}
+	}
+	return rows, nil
+}
+
+// 模拟读取整行的开销
+func copyString(s string) string {
+	return string([]byte(s))
+}
+
+type tableConnector struct {
+	d *tableDriver
+}
+
+func (c tableConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c tableConnector) Driver() driver.Driver                        { return c.d }
+
+func tableDB(t testing.TB, d *tableDriver, l logger.Interface) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(testDialector{name: "sqlite", pool: sql.OpenDB(tableConnector{d})},
+		&gorm.Config{Logger: l, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestDeferredJoin(t *testing.T) {
+	rec := &sqlRecorder{}
+	db := tableDB(t, newTableDriver(100, 8), rec)
+	f := &Filter{DeferredJoin: true, SkipCount: true, Page: 3, PageSize: 5, Sortable: []string{"name"}, Sort: "-name",
+		Filters: map[string]interface{}{"status": 1}}
+	items, _, _, _, err := QueryWithPagination[item](db, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 5 || items[0].ID != 11 {
+		t.Fatalf("want 5 items from id 11, got %d items", len(items))
+	}
+	sqls := rec.all()
+	if len(sqls) != 2 {
+		t.Fatalf("want 2 statements, got %v", sqls)
+	}
+	// 先按原排序和 OFFSET 只查询主键，多取一条判断下一页
+	assertContains(t, sqls[0], `SELECT "items"."id" FROM "items" WHERE "status" = 1`, `ORDER BY "name" DESC LIMIT 6 OFFSET 10`)
+	// 再按主键查询完整记录，保留原排序，不再分页
+	assertContains(t, sqls[1], `SELECT * FROM "items" WHERE "status" = 1`, `"items"."id" IN (11,12,13,14,15,16)`, `ORDER BY "name" DESC`)
+	assertNotContains(t, sqls[1], "LIMIT", "OFFSET")
+}
+
+func TestDeferredJoinFirstPage(t *testing.T) {
+	rec := &sqlRecorder{}
+	db := tableDB(t, newTableDriver(20, 8), rec)
+	f := &Filter{DeferredJoin: true, SkipCount: true, Page: 1, PageSize: 5}
+	if _, _, _, _, err := QueryWithPagination[item](db, f); err != nil {
+		t.Fatal(err)
+	}
+	if sqls := rec.all(); len(sqls) != 1 {
+		t.Errorf("first page should use a single statement, got %v", sqls)
+	}
+}
+
+// 10 万行、每行 1KB 的模拟表上翻到第 500 页（OFFSET 9980），DeferredJoin 只读取跳过行的主键
+func BenchmarkDeferredJoin(b *testing.B) {
+	db := tableDB(b, newTableDriver(100000, 1024), logger.Discard)
+	for _, deferred := range []bool{false, true} {
+		name := "offset"
+		if deferred {
+			name = "deferred"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				f := &Filter{DeferredJoin: deferred, SkipCount: true, Page: 500, PageSize: 20}
+				if _, _, _, _, err := QueryWithPagination[item](db, f); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// 测试用方言，按名称模拟 sqlite、mysql、postgres 的引号、占位符和 ON CONFLICT 写法；
+// 没有 pool 时只能在 DryRun 下生成 SQL
+type testDialector struct {
+	name string
+	pool gorm.ConnPool
+}
+
+func (d testDialector) Name() string { return d.name }
+
+func (d testDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	if d.name == "mysql" {
+		db.ClauseBuilders["ON CONFLICT"] = mysqlOnConflict
+	}
+	if d.pool != nil {
+		db.ConnPool = d.pool
+	}
+	return nil
+}
+
+// 同 gorm.io/driver/mysql：ON DUPLICATE KEY UPDATE，excluded 列改写为 VALUES(列)
+func mysqlOnConflict(c clause.Clause, builder clause.Builder) {
+	onConflict, ok := c.Expression.(clause.OnConflict)
+	if !ok {
+		c.Build(builder)
+		return
+	}
+	builder.WriteString("ON DUPLICATE KEY UPDATE ")
+	for i, assignment := range onConflict.DoUpdates {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteQuoted(assignment.Column)
+		builder.WriteByte('=')
+		if column, ok := assignment.Value.(clause.Column); ok && column.Table == "excluded" {
+			column.Table = ""
+			builder.WriteString("VALUES(")
+			builder.WriteQuoted(column)
+			builder.WriteByte(')')
+		} else {
+			builder.AddVar(builder, assignment.Value)
+		}
+	}
+}
+
+func (d testDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	return migrator.Migrator{Config: migrator.Config{DB: db, Dialector: d}}
+}
+
+func (d testDialector) DataTypeOf(*schema.Field) string { return "" }
+
+func (d testDialector) DefaultValueOf(*schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (d testDialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	if d.name == "postgres" {
+		writer.WriteString(fmt.Sprintf("$%d", len(stmt.Vars)))
+		return
+	}
+	writer.WriteByte('?')
+}
+
+func (d testDialector) QuoteTo(writer clause.Writer, str string) {
+	quote := `"`
+	if d.name == "mysql" {
+		quote = "`"
+	}
+	for i, part := range strings.Split(str, ".") {
+		if i > 0 {
+			writer.WriteByte('.')
+		}
+		if part == "*" {
+			writer.WriteString(part)
+			continue
+		}
+		writer.WriteString(quote + part + quote)
+	}
+}
+
+// postgres 的编号占位符 $1、$2
+var numericPlaceholder = regexp.MustCompile(`\$(\d+)`)
+
+func (d testDialector) Explain(sql string, vars ...interface{}) string {
+	if d.name == "postgres" {
+		return logger.ExplainSQL(sql, numericPlaceholder, `'`, vars...)
+	}
+	return logger.ExplainSQL(sql, nil, `'`, vars...)
+}
+
+// 记录执行的 SQL（参数已代入）
+type sqlRecorder struct {
+	mu   sync.Mutex
+	sqls []string
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface      { return r }
+func (r *sqlRecorder) Info(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Warn(context.Context, string, ...interface{})  {}
+func (r *sqlRecorder) Error(context.Context, string, ...interface{}) {}
+
+func (r *sqlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	r.mu.Lock()
+	r.sqls = append(r.sqls, sql)
+	r.mu.Unlock()
+}
+
+// 最后一条 SQL，没有执行任何语句时为空
+func (r *sqlRecorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sqls) == 0 {
+		return ""
+	}
+	return r.sqls[len(r.sqls)-1]
+}
+
+func (r *sqlRecorder) all() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.sqls...)
+}
+
+// 指定方言的 DryRun 连接，返回的 recorder 记录生成的 SQL
+func dryRunDB(t testing.TB, dialect string) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+	rec := &sqlRecorder{}
+	db, err := gorm.Open(testDialector{name: dialect}, &gorm.Config{DryRun: true, Logger: rec})
+	if err != nil {
+		t.Fatalf("open %s: %v", dialect, err)
+	}
+	return db, rec
+}
+
+// 断言 sql 包含全部片段
+func assertContains(t *testing.T, sql string, parts ...string) {
+	t.Helper()
+	for _, p := range parts {
+		if !strings.Contains(sql, p) {
+			t.Errorf("sql does not contain %q\nsql: %s", p, sql)
+		}
+	}
+}
+
+// 断言 sql 不包含任何片段
+func assertNotContains(t *testing.T, sql string, parts ...string) {
+	t.Helper()
+	for _, p := range parts {
+		if strings.Contains(sql, p) {
+			t.Errorf("sql unexpectedly contains %q\nsql: %s", p, sql)
+		}
+	}
+}
+
+// 测试模型
+type item struct {
+	ID        uint
+	Name      string
+	Status    int
+	Stock     int
+	IsDeleted int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+// 按 Filter 生成列表查询的 SQL
+func findSQL(t *testing.T, db *gorm.DB, rec *sqlRecorder, f *Filter) (string, error) {
+	t.Helper()
+	queryDB, err := f.PaginationQueryE(db.Model(&item{}))
+	if err != nil {
+		return "", err
+	}
+	queryDB, err = f.ApplySortAndPaginationE(queryDB)
+	if err != nil {
+		return "", err
+	}
+	var items []item
+	if err := queryDB.Find(&items).Error; err != nil {
+		return "", err
+	}
+	return rec.last(), nil
+}
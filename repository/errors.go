@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// FilterError 筛选条件错误，Field 为字段名，Op 为运算符（可能为空），Reason 为原因
+type FilterError struct {
+	Field  string `json:"field"`
+	Op     string `json:"op,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (e FilterError) Error() string {
+	return "filter " + strings.TrimSpace(e.Field+" "+e.Op) + ": " + e.Reason
+}
+
+// 合并多个筛选条件错误，可通过 errors.As 取出单个 FilterError
+func joinFilterErrors(errs []FilterError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	list := make([]error, len(errs))
+	for i, e := range errs {
+		list[i] = e
+	}
+	return errors.Join(list...)
+}
+
+// ErrPageOutOfRange 请求的页码超出 MaxOffset 允许的范围，可通过 errors.Is 判断，
+// 通过 errors.As 取出 *PageOutOfRangeError 获取允许的最大页码
+var ErrPageOutOfRange = errors.New("page out of range")
+
+// PageOutOfRangeError 页码超出范围的错误，MaxPage 为允许的最大页码
+type PageOutOfRangeError struct {
+	Page    int `json:"page"`
+	MaxPage int `json:"max_page"`
+}
+
+func (e *PageOutOfRangeError) Error() string {
+	return fmt.Sprintf("page %d out of range, max page %d", e.Page, e.MaxPage)
+}
+
+func (e *PageOutOfRangeError) Is(target error) bool {
+	return target == ErrPageOutOfRange
+}
+
+// ErrNoConditions Filter 没有产生任何筛选条件，为避免误操作整张表，批量更新、删除拒绝执行
+var ErrNoConditions = errors.New("filter has no conditions")
+
+// ErrBelowFloor DecrementWithFloor 扣减后的值将小于 0，未执行扣减
+var ErrBelowFloor = errors.New("value would drop below zero")
+
+// ErrEmptyUpdates 更新的内容为空
+var ErrEmptyUpdates = errors.New("updates cannot be empty")
+
+// ErrProtectedColumn 更新的列受保护（如 id、created_at），不允许修改
+var ErrProtectedColumn = errors.New("column is protected")
+
+// ErrColumnNotUpdatable 严格模式下更新的列不在可更新列的白名单中
+var ErrColumnNotUpdatable = errors.New("column is not updatable")
+
+// ErrNotFound 按条件查询时没有匹配的记录，可通过 errors.Is 判断，
+// 通过 errors.As 取出 *NotFoundError 获取模型名；同时仍可通过 errors.Is 判断为 gorm.ErrRecordNotFound
+var ErrNotFound = errors.New("record not found")
+
+// NotFoundError 没有匹配记录的错误，Model 为模型名
+type NotFoundError struct {
+	Model string `json:"model"`
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Model + " not found"
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return gorm.ErrRecordNotFound
+}
+
+// gorm.ErrRecordNotFound 转为 *NotFoundError，其他错误原样返回
+func wrapNotFound[T any](err error) error {
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return &NotFoundError{Model: reflect.TypeOf((*T)(nil)).Elem().Name()}
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// 按 id 游标分页：AfterID 生成 id > ? ORDER BY id ASC，BeforeID 生成 id < ? ORDER BY id DESC，不使用 OFFSET
+func (f *Filter) applyKeyset(db *gorm.DB) *gorm.DB {
+	if f.AfterID > 0 && f.BeforeID > 0 {
+		f.addError("after_id", "", "cannot be combined with before_id")
+		return db
+	}
+	if f.Page > 1 {
+		f.addError("page", "", "offset pagination cannot be combined with after_id or before_id")
+		return db
+	}
+	op, order, sort, id := ">", "ASC", "id", f.AfterID
+	if f.BeforeID > 0 {
+		op, order, sort, id = "<", "DESC", "-id", f.BeforeID
+	}
+	if f.Sort != "" && !f.keysetSortMatches(order) {
+		f.addError("sort", "", fmt.Sprintf("keyset pagination requires sort %q", sort))
+		return db
+	}
+	column := quoteIdent(db, f.keysetColumn())
+	db = db.Where(fmt.Sprintf("%s %s ?", column, op), id).Order(fmt.Sprintf("%s %s", column, order))
+	f.recordSQL(fmt.Sprintf("KEYSET %s %s", op, order), id)
+	return db
+}
+
+// Sort 是否只按 id 排序且方向为 order，兼容 "+id"、"id asc"、"表名.id" 等写法
+func (f *Filter) keysetSortMatches(order string) bool {
+	if strings.Contains(f.Sort, ",") {
+		return false
+	}
+	t, err := parseSortTerm(strings.TrimSpace(f.Sort))
+	if err != nil || t.ci || t.order != order {
+		return false
+	}
+	return t.field == "id" || t.field == f.keysetColumn()
+}
+
+// 游标使用的 id 列，已解析模型时带上表名，避免 JOIN 时列名冲突
+func (f *Filter) keysetColumn() string {
+	if f.schema != nil && f.schema.Table != "" {
+		return f.schema.Table + ".id"
+	}
+	return "id"
+}
+
+// QueryWithKeyset 按 id 游标分页查询，不统计总数，返回本页最后一条记录的 id，作为下一页的 AfterID（BeforeID 时为上一页的 BeforeID）。
+// AfterID 和 BeforeID 都为空时查询第一页，Sort 为空时按 id 升序
+func QueryWithKeyset[T any](db *gorm.DB, f *Filter) ([]T, uint, error) {
+	// 在副本上补充默认排序，不修改调用方的 Filter
+	c := f.Clone()
+	if c.AfterID == 0 && c.BeforeID == 0 && c.Sort == "" {
+		c.Sort = "id"
+	}
+	queryDB, err := c.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return nil, 0, err
+	}
+	if queryDB, err = c.ApplySortAndPaginationE(queryDB); err != nil {
+		return nil, 0, err
+	}
+	if c.Debug {
+		c.PrintSQLs()
+	}
+	var result []T
+	if err := queryDB.Find(&result).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(result) == 0 || c.schema == nil {
+		return result, 0, nil
+	}
+	return result, recordID(c.schema, reflect.ValueOf(&result[len(result)-1]).Elem()), nil
+}
+
+// 取记录的 id 字段值，没有 id 字段时使用主键
+func recordID(sch *schema.Schema, v reflect.Value) uint {
+	field := sch.LookUpField("id")
+	if field == nil {
+		field = sch.PrioritizedPrimaryField
+	}
+	if field == nil {
+		return 0
+	}
+	val, _ := field.ValueOf(context.Background(), v)
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.Int() > 0 {
+			return uint(rv.Int())
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uint(rv.Uint())
+	}
+	return 0
+}
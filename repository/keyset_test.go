@@ -0,0 +1,39 @@
+package repository
+
+import "testing"
+
+func TestQueryWithKeysetDoesNotModifyFilter(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{PageSize: 2}
+	if _, _, err := QueryWithKeyset[item](db, f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Sort != "" {
+		t.Fatalf("caller's sort changed to %q", f.Sort)
+	}
+	f.BeforeID = 5
+	if _, _, err := QueryWithKeyset[item](db, f); err != nil {
+		t.Fatalf("reused filter: %v", err)
+	}
+	assertContains(t, rec.last(), `"items"."id" < 5`, `ORDER BY "items"."id" DESC`, "LIMIT 2")
+}
+
+func TestKeysetSortForms(t *testing.T) {
+	db, _ := dryRunDB(t, "sqlite")
+	for _, sort := range []string{"id", "+id", "id asc", "id:ASC", "items.id", " id "} {
+		f := &Filter{AfterID: 3, Sort: sort}
+		if _, _, err := QueryWithKeyset[item](db, f); err != nil {
+			t.Errorf("sort %q: %v", sort, err)
+		}
+	}
+	for _, sort := range []string{"-id", "id desc", "name", "id,name", "roles.id", "id:ci"} {
+		f := &Filter{AfterID: 3, Sort: sort}
+		if _, _, err := QueryWithKeyset[item](db, f); err == nil {
+			t.Errorf("sort %q: want error", sort)
+		}
+	}
+	f := &Filter{BeforeID: 3, Sort: "id DESC"}
+	if _, _, err := QueryWithKeyset[item](db, f); err != nil {
+		t.Errorf("before_id with %q: %v", f.Sort, err)
+	}
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// PageLinks 分页链接，可直接用于响应体或 Link 头。没有对应页时链接为空、页码为 nil
+type PageLinks struct {
+	Self     string `json:"self"`
+	First    string `json:"first"`
+	Last     string `json:"last,omitempty"` //总数未知（SkipCount）时为空
+	Next     string `json:"next,omitempty"`
+	Prev     string `json:"prev,omitempty"`
+	NextPage *int   `json:"next_page"`
+	PrevPage *int   `json:"prev_page"`
+}
+
+// PageInfo 以 baseURL 为基础生成各页链接，替换其中的 page、page_size 参数，保留其余查询参数。
+// 最后一页或结果为空时没有下一页；页码超出范围时上一页指向最后一页。baseURL 无法解析时只返回页码
+func (p *PageResult[T]) PageInfo(baseURL string) PageLinks {
+	var links PageLinks
+	page := p.Page
+	if page < 1 {
+		page = 1
+	}
+	if p.HasNext {
+		next := page + 1
+		links.NextPage = &next
+	}
+	if page > 1 {
+		prev := page - 1
+		if p.TotalPages >= 0 && prev > p.TotalPages {
+			prev = p.TotalPages
+		}
+		if prev >= 1 {
+			links.PrevPage = &prev
+		}
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return links
+	}
+	link := func(n int) string {
+		q := u.Query()
+		q.Set("page", strconv.Itoa(n))
+		if p.PageSize > 0 {
+			q.Set("page_size", strconv.Itoa(p.PageSize))
+		}
+		l := *u
+		l.RawQuery = q.Encode()
+		return l.String()
+	}
+	links.Self = link(page)
+	links.First = link(1)
+	switch {
+	case p.TotalPages > 0:
+		links.Last = link(p.TotalPages)
+	case p.TotalPages == 0:
+		// 结果为空时只有第一页
+		links.Last = links.First
+	}
+	if links.NextPage != nil {
+		links.Next = link(*links.NextPage)
+	}
+	if links.PrevPage != nil {
+		links.Prev = link(*links.PrevPage)
+	}
+	return links
+}
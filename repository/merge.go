@@ -0,0 +1,143 @@
+package repository
+
+// Merge 合并两个 Filter，返回新的 Filter，不修改 f 和 other，规则同 MergeFilters
+func (f *Filter) Merge(other *Filter) *Filter {
+	return MergeFilters(f, other)
+}
+
+// MergeFilters 按顺序合并多个 Filter（如中间件的租户条件、接口的用户条件、服务层的状态条件），nil 跳过：
+//   - Filters、BaseConditions 中同名字段不覆盖，两个条件都保留并以 AND 连接，eq 值冲突时查询结果为空
+//   - Joins 拼接并去重，RawConditions、Exists、InSubqueries 拼接，命名表达式合并
+//   - Filterable、Sortable 取并集，空列表不参与合并；Filterable 为空（不限制字段）的 Filter，
+//     其 Filters 视为可信条件移入 BaseConditions，不受合并后的 Filterable 限制，避免租户等条件被丢弃
+//   - Page、PageSize 取非零值中较小的一个
+//   - QueryStr、Sort 取第一个非空值，Strict、Debug 任一开启即开启，其余字段以第一个 Filter 为准
+func MergeFilters(fs ...*Filter) *Filter {
+	res := &Filter{}
+	first := true
+	for _, f := range fs {
+		if f == nil {
+			continue
+		}
+		if first {
+			*res = *f
+			res.Filters = nil
+			res.BaseConditions = nil
+			res.Joins = nil
+			res.Filterable = nil
+			res.Sortable = nil
+			res.RawConditions = append([]RawCondition(nil), f.RawConditions...)
+			res.Exists = append([]ExistsCondition(nil), f.Exists...)
+			res.InSubqueries = append([]InSubquery(nil), f.InSubqueries...)
+			res.exprs = nil
+			res.resetState()
+			first = false
+		} else {
+			res.RawConditions = append(res.RawConditions, f.RawConditions...)
+			res.Exists = append(res.Exists, f.Exists...)
+			res.InSubqueries = append(res.InSubqueries, f.InSubqueries...)
+			if res.QueryStr == "" {
+				res.QueryStr, res.QuerySyntax = f.QueryStr, f.QuerySyntax
+			}
+			if res.Sort == "" {
+				res.Sort = f.Sort
+			}
+			res.Page = minPositive(res.Page, f.Page)
+			res.PageSize = minPositive(res.PageSize, f.PageSize)
+			res.Strict = res.Strict || f.Strict
+			res.Debug = res.Debug || f.Debug
+		}
+		if len(f.Filterable) == 0 {
+			res.BaseConditions = mergeConditions(res.BaseConditions, f.Filters)
+		} else {
+			res.Filters = mergeConditions(res.Filters, f.Filters)
+		}
+		res.BaseConditions = mergeConditions(res.BaseConditions, f.BaseConditions)
+		for name, sql := range f.exprs {
+			res.RegisterExpr(name, sql)
+		}
+		for _, j := range f.Joins {
+			if !containsJoin(res.Joins, j) {
+				res.Joins = append(res.Joins, j)
+			}
+		}
+		res.Filterable = unionStrings(res.Filterable, f.Filterable)
+		res.Sortable = unionStrings(res.Sortable, f.Sortable)
+	}
+	return res
+}
+
+// 合并条件 map，同名字段移入 and 条件组，已有的 and 条件组直接拼接
+func mergeConditions(dst, src map[string]interface{}) map[string]interface{} {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+	for k, v := range src {
+		old, ok := dst[k]
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		var group []interface{}
+		if and, ok := dst["and"].([]interface{}); ok {
+			group = append(group, and...)
+		} else if and, ok := conditionMaps(dst["and"]); ok {
+			for _, m := range and {
+				group = append(group, m)
+			}
+		}
+		if k == "and" {
+			if items, ok := conditionMaps(v); ok {
+				for _, m := range items {
+					group = append(group, m)
+				}
+				dst["and"] = group
+				continue
+			}
+			// 格式有误时原样保留两个条件组，由 applyGroup 报错
+			group = append(group, map[string]interface{}{"and": v})
+			dst["and"] = group
+			continue
+		}
+		delete(dst, k)
+		group = append(group, map[string]interface{}{k: old}, map[string]interface{}{k: v})
+		dst["and"] = group
+	}
+	return dst
+}
+
+func containsJoin(joins []JoinConfig, j JoinConfig) bool {
+	for _, item := range joins {
+		if item == j {
+			return true
+		}
+	}
+	return false
+}
+
+// 并集，保持原有顺序，结果不与参数共用底层数组
+func unionStrings(a, b []string) []string {
+	res := append([]string(nil), a...)
+	for _, s := range b {
+		if !containsString(res, s) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// 取非零值中较小的一个
+func minPositive(a, b int) int {
+	switch {
+	case a <= 0:
+		return b
+	case b <= 0:
+		return a
+	case b < a:
+		return b
+	}
+	return a
+}
@@ -0,0 +1,30 @@
+package repository
+
+import "testing"
+
+func TestMergeFiltersKeepsTrustedConditions(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	tenant := &Filter{Filters: map[string]interface{}{"tenant_id": 7}}
+	handler := &Filter{Filterable: []string{"name"}, Filters: map[string]interface{}{"name": "x"}}
+	f := MergeFilters(tenant, handler)
+	sql, err := findSQL(t, db, rec, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, `"tenant_id" = 7`, `"name" = 'x'`)
+	if len(tenant.BaseConditions) != 0 || len(handler.BaseConditions) != 0 {
+		t.Error("inputs should not be modified")
+	}
+}
+
+func TestMergeFiltersSameField(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	a := &Filter{Filterable: []string{"status"}, Filters: map[string]interface{}{"status": map[string]interface{}{"gte": 1}}}
+	b := &Filter{Filterable: []string{"status"}, Filters: map[string]interface{}{"status": map[string]interface{}{"lte": 3}}, PageSize: 5}
+	f := MergeFilters(a, nil, b)
+	sql, err := findSQL(t, db, rec, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, `"status" >= 1`, `"status" <= 3`, "LIMIT 5")
+}
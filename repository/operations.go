@@ -1,36 +1,354 @@
 package repository
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
 )
 
-// GetInfoById 通用的根据id获取详细
-func GetInfoById[T any](db *gorm.DB, id uint) (*T, error) {
+// GetInfoById 通用的根据id获取详细，可通过选项指定查询的列、预加载关联等，如
+//
+//	GetInfoById[User](db, id, WithOmit("payload"), WithPreload("Roles", "Profile"))
+func GetInfoById[T any](db *gorm.DB, id uint, opts ...QueryOption) (*T, error) {
+	return GetInfoByIdOpt[T](db, id, opts...)
+}
+
+// QueryOption GetInfoById、GetInfoByIdOpt 的查询选项
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	selects  []string
+	omits    []string
+	preloads []string
+	unscoped bool
+}
+
+// WithSelect 只查询指定的列
+func WithSelect(columns ...string) QueryOption {
+	return func(o *queryOptions) {
+		o.selects = append(o.selects, columns...)
+	}
+}
+
+// WithColumns 同 WithSelect
+func WithColumns(columns ...string) QueryOption {
+	return WithSelect(columns...)
+}
+
+// WithOmit 不查询指定的列，如大字段
+func WithOmit(columns ...string) QueryOption {
+	return func(o *queryOptions) {
+		o.omits = append(o.omits, columns...)
+	}
+}
+
+// WithPreload 预加载关联，名称为模型中的字段名（如 "Roles"），直接传给 gorm 的 Preload
+func WithPreload(associations ...string) QueryOption {
+	return func(o *queryOptions) {
+		o.preloads = append(o.preloads, associations...)
+	}
+}
+
+// WithUnscoped 包含软删除的记录
+func WithUnscoped() QueryOption {
+	return func(o *queryOptions) {
+		o.unscoped = true
+	}
+}
+
+// GetInfoByIdOpt 同 GetInfoById
+func GetInfoByIdOpt[T any](db *gorm.DB, id uint, opts ...QueryOption) (*T, error) {
 	if id == 0 {
 		return nil, errors.New("id cannot be zero")
 	}
-	var res *T
-	err := db.Model(new(T)).
-		Where("id = ?", id).
-		Last(&res).Error
+	var o queryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	tx := db.Model(new(T))
+	if o.unscoped {
+		tx = tx.Unscoped()
+	}
+	for _, col := range append(append([]string(nil), o.selects...), o.omits...) {
+		if !identifierPattern.MatchString(col) {
+			return nil, fmt.Errorf("invalid column %q", col)
+		}
+	}
+	if len(o.selects) > 0 {
+		tx = tx.Select(o.selects)
+	}
+	if len(o.omits) > 0 {
+		tx = tx.Omit(o.omits...)
+	}
+	for _, name := range o.preloads {
+		tx = tx.Preload(name)
+	}
+	res := new(T)
+	err := tx.Where("id = ?", id).
+		First(res).Error
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetByIds 根据ID列表一次查询多条记录，结果按 ids 的顺序排列，不存在的ID忽略
+func GetByIds[T any](db *gorm.DB, ids []uint) ([]T, error) {
+	res, _, err := getByIds[T](db, ids)
+	return res, err
+}
+
+// GetMapByIds 同 GetByIds，返回以 id 为键的 map，id 通过模型的 id 字段（没有时为主键）取得
+func GetMapByIds[T any](db *gorm.DB, ids []uint) (map[uint]T, error) {
+	res, sch, err := getByIds[T](db, ids)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[uint]T, len(res))
+	for i := range res {
+		m[recordID(sch, reflect.ValueOf(&res[i]).Elem())] = res[i]
+	}
+	return m, nil
+}
+
+func getByIds[T any](db *gorm.DB, ids []uint) ([]T, *schema.Schema, error) {
+	if len(ids) == 0 {
+		return []T{}, nil, nil
+	}
+	var res []T
+	tx := db.Model(new(T)).
+		Where("id IN ?", ids).
+		Find(&res)
+	if tx.Error != nil {
+		return nil, nil, tx.Error
+	}
+	sch := tx.Statement.Schema
+	if sch == nil {
+		return res, nil, nil
+	}
+	// 按传入的顺序排列
+	pos := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		if _, ok := pos[id]; !ok {
+			pos[id] = i
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return pos[recordID(sch, reflect.ValueOf(&res[i]).Elem())] < pos[recordID(sch, reflect.ValueOf(&res[j]).Elem())]
+	})
+	return res, sch, nil
+}
+
+// GetOneByFilter 按 Filter 的筛选条件和排序查询第一条记录，忽略分页，没有匹配的记录时返回 *NotFoundError
+func GetOneByFilter[T any](db *gorm.DB, f *Filter) (*T, error) {
+	queryDB, err := f.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return nil, err
+	}
+	noPagination := f.NoPagination
+	f.NoPagination = true
+	queryDB, err = f.ApplySortAndPaginationE(queryDB)
+	f.NoPagination = noPagination
 	if err != nil {
 		return nil, err
 	}
+	if f.Debug {
+		f.PrintSQLs()
+	}
+	res := new(T)
+	if err := queryDB.Take(res).Error; err != nil {
+		return nil, wrapNotFound[T](err)
+	}
+	return res, nil
+}
+
+// FirstByField 查询 field = value 的第一条记录（按主键排序），没有匹配的记录时返回 *NotFoundError
+func FirstByField[T any](db *gorm.DB, field string, value interface{}) (*T, error) {
+	if !identifierPattern.MatchString(field) {
+		return nil, fmt.Errorf("invalid field %q", field)
+	}
+	res := new(T)
+	err := db.Model(new(T)).
+		Where(fmt.Sprintf("%s = ?", quoteIdent(db, field)), value).
+		First(res).Error
+	if err != nil {
+		return nil, wrapNotFound[T](err)
+	}
 	return res, nil
 }
 
+// ExistsById 判断指定ID的记录是否存在，只查询 SELECT 1 ... LIMIT 1
+func ExistsById[T any](db *gorm.DB, id uint) (bool, error) {
+	if id == 0 {
+		return false, errors.New("id cannot be zero")
+	}
+	return exists(db.Model(new(T)).Where("id = ?", id))
+}
+
+// ExistsByFilter 判断是否存在匹配 Filter 筛选条件的记录，忽略查询列、排序和分页
+func ExistsByFilter[T any](db *gorm.DB, f *Filter) (bool, error) {
+	queryDB, err := f.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return false, err
+	}
+	if f.Debug {
+		f.PrintSQLs()
+	}
+	return exists(queryDB)
+}
+
+func exists(db *gorm.DB) (bool, error) {
+	var hit int
+	result := db.Select("1").Limit(1).Scan(&hit)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 // Created 创建
 func Created[T any](db *gorm.DB, m *T) error {
 	return db.Create(m).Error
 }
 
-// UpdateByIdWithMap 通用的根据ID删除记录
+// DefaultBatchSize BatchCreate 未指定 batchSize 时每批插入的条数
+const DefaultBatchSize = 500
+
+// BatchCreate 分批插入，batchSize <= 0 时使用 DefaultBatchSize，返回插入的行数。
+// 未开启 SkipDefaultTransaction 时所有批次在同一事务中执行，任一批失败全部回滚；错误中包含失败的批次
+func BatchCreate[T any](db *gorm.DB, items []T, batchSize int) (int64, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	var rows int64
+	insert := func(tx *gorm.DB) error {
+		for start := 0; start < len(items); start += batchSize {
+			end := min(start+batchSize, len(items))
+			result := tx.CreateInBatches(items[start:end], batchSize)
+			if result.Error != nil {
+				return fmt.Errorf("batch create: batch %d (rows %d-%d) failed: %w", start/batchSize+1, start, end-1, result.Error)
+			}
+			rows += result.RowsAffected
+		}
+		return nil
+	}
+	var err error
+	if db.SkipDefaultTransaction || len(items) <= batchSize {
+		err = insert(db)
+	} else {
+		err = db.Transaction(insert)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return rows, nil
+}
+
+// Upsert 插入，冲突时更新。conflictColumns 为唯一键列（MySQL 忽略，按表上的唯一索引判断），为空时使用主键；
+// updateColumns 为冲突时更新的列，为空时更新全部列
+func Upsert[T any](db *gorm.DB, m *T, conflictColumns []string, updateColumns []string) error {
+	onConflict, err := upsertClause(conflictColumns, updateColumns)
+	if err != nil {
+		return err
+	}
+	return db.Clauses(onConflict).Create(m).Error
+}
+
+// UpsertBatch 同 Upsert，按 DefaultBatchSize 分批写入，返回影响的行数（MySQL 上更新的行计为 2）
+func UpsertBatch[T any](db *gorm.DB, items []T, conflictColumns []string, updateColumns []string) (int64, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	onConflict, err := upsertClause(conflictColumns, updateColumns)
+	if err != nil {
+		return 0, err
+	}
+	result := db.Clauses(onConflict).CreateInBatches(items, DefaultBatchSize)
+	return result.RowsAffected, result.Error
+}
+
+// 生成 ON CONFLICT / ON DUPLICATE KEY UPDATE 子句，列名需为合法的标识符
+func upsertClause(conflictColumns []string, updateColumns []string) (clause.OnConflict, error) {
+	onConflict := clause.OnConflict{UpdateAll: len(updateColumns) == 0}
+	for _, col := range append(append([]string(nil), conflictColumns...), updateColumns...) {
+		if !identifierPattern.MatchString(col) {
+			return onConflict, fmt.Errorf("upsert: invalid column %q", col)
+		}
+	}
+	for _, col := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: col})
+	}
+	if len(updateColumns) > 0 {
+		onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+	}
+	return onConflict, nil
+}
+
+// FirstOrCreate 按 where 查询主键最小的一条记录，存在时直接返回（不使用 attrs），不存在时创建，返回记录和是否新建。
+// 新记录的字段取自 where 中的等值条件（列表值生成 IN，不赋值）和 attrs 中的非零字段，attrs 的零值字段不写入。
+// 并发调用时两个调用方可能同时判断为不存在，需在 where 的列上建唯一索引；
+// 开启 gorm.Config.TranslateError 后，创建因唯一键冲突失败时会重新查询一次并返回已存在的记录，否则返回创建的错误
+func FirstOrCreate[T any](db *gorm.DB, where map[string]interface{}, attrs *T) (*T, bool, error) {
+	if len(where) == 0 {
+		return nil, false, errors.New("first or create: where cannot be empty")
+	}
+	for col := range where {
+		if !identifierPattern.MatchString(col) {
+			return nil, false, fmt.Errorf("first or create: invalid column %q", col)
+		}
+	}
+	tx := db.Model(new(T)).Where(where)
+	if attrs != nil {
+		tx = tx.Attrs(attrs)
+	}
+	res := new(T)
+	result := tx.FirstOrCreate(res)
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		// 其他调用方已创建，重新查询
+		res = new(T)
+		if err := db.Model(new(T)).Where(where).First(res).Error; err != nil {
+			return nil, false, err
+		}
+		return res, false, nil
+	}
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	// 查询到记录时 gorm 在另一个会话中查询，返回的 RowsAffected 为 0
+	return res, result.RowsAffected > 0, nil
+}
+
+// DefaultProtectedColumns UpdateByIdWithMap 不允许修改的列，仓储可通过 WithProtectedColumns 追加
+var DefaultProtectedColumns = []string{"id", "created_at"}
+
+// UpdateByIdWithMap 通用的根据ID更新记录。updates 为空时返回 ErrEmptyUpdates，
+// 包含 DefaultProtectedColumns 中的列时返回 ErrProtectedColumn
 func UpdateByIdWithMap[T any](db *gorm.DB, id uint, updates map[string]interface{}) error {
+	return updateByIdWithMap[T](db, id, updates, DefaultProtectedColumns)
+}
+
+func updateByIdWithMap[T any](db *gorm.DB, id uint, updates map[string]interface{}, protected []string) error {
 	if id == 0 {
 		return errors.New("id cannot be zero")
 	}
+	if len(updates) == 0 {
+		return ErrEmptyUpdates
+	}
+	if err := checkProtected(updates, protected); err != nil {
+		return err
+	}
 
 	result := db.Model(new(T)).
 		Where("id = ?", id).
@@ -46,47 +364,505 @@ func UpdateByIdWithMap[T any](db *gorm.DB, id uint, updates map[string]interface
 	return nil
 }
 
-// QueryWithPagination 通用分页查询函数
+// UpdateByIdWithMapAllowed 同 UpdateByIdWithMap，只更新 allowed 中的列（支持 "表名.*"），类似查询的 Filterable。
+// 白名单以外的列默认忽略，strict 为 true 时返回 ErrColumnNotUpdatable 并列出这些列；忽略后为空时返回 ErrEmptyUpdates
+func UpdateByIdWithMapAllowed[T any](db *gorm.DB, id uint, updates map[string]interface{}, allowed []string, strict bool) error {
+	updates, err := allowedUpdates(updates, allowed, strict)
+	if err != nil {
+		return err
+	}
+	return UpdateByIdWithMap[T](db, id, updates)
+}
+
+// 按白名单筛选更新的列
+func allowedUpdates(updates map[string]interface{}, allowed []string, strict bool) (map[string]interface{}, error) {
+	res := make(map[string]interface{}, len(updates))
+	var rejected []string
+	for key, v := range updates {
+		if matchWhitelist(allowed, key) {
+			res[key] = v
+		} else {
+			rejected = append(rejected, key)
+		}
+	}
+	if strict && len(rejected) > 0 {
+		sort.Strings(rejected)
+		return nil, fmt.Errorf("%w: %s", ErrColumnNotUpdatable, strings.Join(rejected, ", "))
+	}
+	return res, nil
+}
+
+// 更新的列中包含受保护的列时返回 ErrProtectedColumn，"表名.列名" 按列名判断，不区分大小写
+func checkProtected(updates map[string]interface{}, protected []string) error {
+	var hit []string
+	for key := range updates {
+		col := key
+		if i := strings.LastIndex(col, "."); i >= 0 {
+			col = col[i+1:]
+		}
+		for _, p := range protected {
+			if strings.EqualFold(col, p) {
+				hit = append(hit, key)
+				break
+			}
+		}
+	}
+	if len(hit) == 0 {
+		return nil
+	}
+	sort.Strings(hit)
+	return fmt.Errorf("%w: %s", ErrProtectedColumn, strings.Join(hit, ", "))
+}
+
+// SaveOption Save 的选项
+type SaveOption func(*saveOptions)
+
+type saveOptions struct {
+	allowInsert bool
+}
+
+// AllowInsert 允许 Save 插入主键为零值的新记录
+func AllowInsert() SaveOption {
+	return func(o *saveOptions) {
+		o.allowInsert = true
+	}
+}
+
+// Save 保存整条记录，包括零值字段。主键为零值时返回错误，避免误插入新记录，需要插入时传 AllowInsert()。
+// 基于 gorm 的 Save：会执行 BeforeSave/BeforeUpdate 等钩子并自动更新 updated_at；
+// created_at 等所有列都按结构体的值写入，需先查询出完整记录再修改；主键对应的记录不存在时 gorm 会改为插入
+func Save[T any](db *gorm.DB, m *T, opts ...SaveOption) error {
+	if m == nil {
+		return errors.New("save value cannot be nil")
+	}
+	var o saveOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if !o.allowInsert {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m); err != nil {
+			return err
+		}
+		pk := stmt.Schema.PrioritizedPrimaryField
+		if pk == nil {
+			return fmt.Errorf("save: %s has no primary key", stmt.Schema.Name)
+		}
+		if _, zero := pk.ValueOf(context.Background(), reflect.ValueOf(m).Elem()); zero {
+			return errors.New("save: primary key is zero, pass AllowInsert() to insert")
+		}
+	}
+	return db.Save(m).Error
+}
+
+// UpdateByIdsWithMap 根据ID列表批量更新，生成一条 UPDATE ... WHERE id IN (?)，返回影响的行数，部分ID不存在时不报错。
+// updates 的校验同 UpdateByIdWithMap
+func UpdateByIdsWithMap[T any](db *gorm.DB, ids []uint, updates map[string]interface{}) (int64, error) {
+	return updateByIdsWithMap[T](db, ids, updates, DefaultProtectedColumns)
+}
+
+func updateByIdsWithMap[T any](db *gorm.DB, ids []uint, updates map[string]interface{}, protected []string) (int64, error) {
+	if len(ids) == 0 {
+		return 0, errors.New("ids cannot be empty")
+	}
+	if len(updates) == 0 {
+		return 0, ErrEmptyUpdates
+	}
+	if err := checkProtected(updates, protected); err != nil {
+		return 0, err
+	}
+	result := db.Model(new(T)).
+		Where("id IN ?", ids).
+		Updates(updates)
+	return result.RowsAffected, result.Error
+}
+
+// UpdateByIdWithStruct 根据ID按结构体更新，只更新非零值字段，不更新 id，没有匹配的记录时返回 gorm.ErrRecordNotFound
+func UpdateByIdWithStruct[T any](db *gorm.DB, id uint, m *T) error {
+	if id == 0 {
+		return errors.New("id cannot be zero")
+	}
+	if m == nil {
+		return errors.New("update value cannot be nil")
+	}
+	return checkUpdated(db.Model(new(T)).Where("id = ?", id).Omit("id").Updates(m))
+}
+
+// UpdateByIdSelect 根据ID按结构体更新 columns 指定的列，零值也会更新
+func UpdateByIdSelect[T any](db *gorm.DB, id uint, m *T, columns []string) error {
+	if id == 0 {
+		return errors.New("id cannot be zero")
+	}
+	if m == nil {
+		return errors.New("update value cannot be nil")
+	}
+	if len(columns) == 0 {
+		return errors.New("columns cannot be empty")
+	}
+	for _, col := range columns {
+		if !identifierPattern.MatchString(col) {
+			return fmt.Errorf("invalid column %q", col)
+		}
+	}
+	return checkUpdated(db.Model(new(T)).Where("id = ?", id).Select(columns).Omit("id").Updates(m))
+}
+
+// 更新结果，没有影响任何行时返回 gorm.ErrRecordNotFound
+func checkUpdated(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// IncrementById 原子地将 column 增加 delta（可为负数），生成 SET column = column + ?，返回影响的行数，记录不存在时返回 gorm.ErrRecordNotFound
+func IncrementById[T any](db *gorm.DB, id uint, column string, delta int64) (int64, error) {
+	if id == 0 {
+		return 0, errors.New("id cannot be zero")
+	}
+	if !identifierPattern.MatchString(column) {
+		return 0, fmt.Errorf("invalid column %q", column)
+	}
+	return affectedOrNotFound(db.Model(new(T)).
+		Where("id = ?", id).
+		UpdateColumn(column, gorm.Expr(fmt.Sprintf("%s + ?", quoteIdent(db, column)), delta)))
+}
+
+// DecrementWithFloor 原子地将 column 减少 delta，扣减后小于 0 时不更新并返回 ErrBelowFloor，适用于库存等场景；
+// 记录不存在时返回 gorm.ErrRecordNotFound
+func DecrementWithFloor[T any](db *gorm.DB, id uint, column string, delta int64) (int64, error) {
+	if id == 0 {
+		return 0, errors.New("id cannot be zero")
+	}
+	if !identifierPattern.MatchString(column) {
+		return 0, fmt.Errorf("invalid column %q", column)
+	}
+	if delta < 0 {
+		return 0, errors.New("delta cannot be negative")
+	}
+	col := quoteIdent(db, column)
+	result := db.Model(new(T)).
+		Where(fmt.Sprintf("id = ? AND %s >= ?", col), id, delta).
+		UpdateColumn(column, gorm.Expr(fmt.Sprintf("%s - ?", col), delta))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return result.RowsAffected, nil
+	}
+	// 区分记录不存在和余量不足
+	found, err := ExistsById[T](db, id)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return 0, ErrBelowFloor
+	}
+	return 0, gorm.ErrRecordNotFound
+}
+
+// QueryWithPagination 通用分页查询函数，f.SkipCount 为 true 时不统计总数，总数返回 -1
 func QueryWithPagination[T any](db *gorm.DB, f *Filter) ([]T, int64, int, int, error) {
+	res, err := queryWithPagination[T](db, f)
+	if err != nil {
+		return nil, 0, f.Page, f.PageSize, err
+	}
+	return res.items, res.total, f.Page, f.PageSize, nil
+}
+
+// 分页查询的结果
+type pageQuery[T any] struct {
+	items       []T
+	total       int64
+	hasNext     bool
+	approximate bool // total 为 Postgres 执行计划估算的行数
+}
+
+// 分页查询，返回本页记录、总数和是否有下一页。
+// SkipCount 时不执行 COUNT，多取一条记录判断是否有下一页，总数为 -1；AfterID/BeforeID 时同样多取一条记录判断；
+// EstimateCount 时在 Postgres 上使用执行计划估算总数，其他数据库或估算失败时执行 COUNT；
+// Parallel 时 COUNT 与列表查询并发执行，事务中退回顺序执行
+func queryWithPagination[T any](db *gorm.DB, f *Filter) (pageQuery[T], error) {
 	var (
+		res    pageQuery[T]
 		result []T
-		count  int64
+		count  int64 = -1
 	)
-	queryDB := f.PaginationQuery(db.Model(new(T)))
-	if err := queryDB.Count(&count).Error; err != nil {
-		return nil, 0, f.Page, f.PageSize, err
+	queryDB, err := f.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return res, err
+	}
+	// 页码超出范围时不执行 COUNT
+	if !f.NoPagination && f.AfterID == 0 && f.BeforeID == 0 {
+		if err := f.checkOffset(); err != nil {
+			return res, err
+		}
+	}
+	// 统计总数，Parallel 时与列表查询并发执行
+	runCount := func(queryDB *gorm.DB) error {
+		if f.EstimateCount && dialectName(db) == "postgres" {
+			count, res.approximate = estimateCount(queryDB)
+		}
+		if !res.approximate {
+			return countQuery(db, f, queryDB).Count(&count).Error
+		}
+		return nil
+	}
+	parallel := f.Parallel && !f.SkipCount && !inTransaction(db)
+	if !f.SkipCount && !parallel {
+		if err := runCount(queryDB); err != nil {
+			return res, err
+		}
+		if count == 0 {
+			res.items, res.total = []T{}, 0
+			return res, nil
+		}
+	}
+	// 并发时列表查询基于新会话构建，不修改统计总数使用的语句
+	listDB := queryDB
+	if parallel {
+		listDB = queryDB.Session(&gorm.Session{})
 	}
-	if count == 0 {
-		return []T{}, 0, f.Page, f.PageSize, nil
+	if listDB, err = f.ApplySortAndPaginationE(listDB); err != nil {
+		return res, err
+	}
+	// 按 id 游标分页时总数不受游标条件限制，同样多取一条记录判断是否有下一页
+	keyset := f.AfterID > 0 || f.BeforeID > 0
+	peek := (f.SkipCount || keyset) && !f.NoPagination
+	if peek {
+		listDB = listDB.Limit(f.PageSize + 1)
 	}
-	queryDB = f.ApplySortAndPagination(queryDB)
 	if f.Debug {
 		f.PrintSQLs()
 	}
-	if err := queryDB.Find(&result).Error; err != nil {
-		return nil, 0, f.Page, f.PageSize, err
+	if parallel {
+		var (
+			wg       sync.WaitGroup
+			countErr error
+		)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			countErr = runCount(queryDB.Session(&gorm.Session{}))
+		}()
+		findErr := findPage(f, listDB, &result)
+		wg.Wait()
+		if err := errors.Join(countErr, findErr); err != nil {
+			return res, err
+		}
+	} else if err := findPage(f, listDB, &result); err != nil {
+		return res, err
+	}
+
+	switch {
+	case peek:
+		if len(result) > f.PageSize {
+			res.hasNext = true
+			result = result[:f.PageSize]
+		}
+	case !f.NoPagination:
+		res.hasNext = int64(f.Page)*int64(f.PageSize) < count
+	}
+	res.items, res.total = result, count
+	return res, nil
+}
+
+// 是否在事务中。事务只有一个连接，不能在多个 goroutine 中同时使用
+func inTransaction(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// PageResult 分页查询结果，SkipCount 时 Total 和 TotalPages 为 -1，HasNext 通过多取一条记录判断；
+// AfterID/BeforeID 时 Total 为匹配筛选条件的总数（不含游标条件），TotalPages 为 -1，HasNext 同样通过多取一条记录判断
+type PageResult[T any] struct {
+	Items       []T   `json:"items"`
+	Total       int64 `json:"total"`
+	Page        int   `json:"page"`
+	PageSize    int   `json:"page_size"`
+	TotalPages  int   `json:"total_pages"`
+	HasNext     bool  `json:"has_next"`
+	HasPrev     bool  `json:"has_prev"`
+	Approximate bool  `json:"approximate"` //Total 为估算值（EstimateCount）
+}
+
+// QueryPage 同 QueryWithPagination，结果中附带总页数和是否有上一页/下一页。
+// 总是开启 StableSort（在 f 的副本上开启，不影响 f 的其他查询），排序值相同的记录在翻页时不会重复或遗漏
+func QueryPage[T any](db *gorm.DB, f *Filter) (*PageResult[T], error) {
+	c := f.Clone()
+	c.StableSort = true
+	page, err := queryWithPagination[T](db, c)
+	if err != nil {
+		return nil, err
+	}
+	items, total := page.items, page.total
+	c.normalizePage()
+	if c.NoPagination {
+		// 不分页时全部记录在同一页
+		c.Page, c.PageSize = 1, len(items)
 	}
+	res := &PageResult[T]{
+		Items:       items,
+		Total:       total,
+		Page:        c.Page,
+		PageSize:    c.PageSize,
+		HasNext:     page.hasNext,
+		HasPrev:     c.Page > 1,
+		Approximate: page.approximate,
+	}
+	switch {
+	case total < 0, c.AfterID > 0 || c.BeforeID > 0:
+		res.TotalPages = -1
+	case c.PageSize > 0:
+		res.TotalPages = int((total + int64(c.PageSize) - 1) / int64(c.PageSize))
+	}
+	return res, nil
+}
 
-	return result, count, f.Page, f.PageSize, nil
+// CountByFilter 统计匹配 Filter 筛选条件的记录数，包括 JOIN 和去重，不排序、不分页；f 为 nil 时统计全部记录
+func CountByFilter[T any](db *gorm.DB, f *Filter) (int64, error) {
+	if f == nil {
+		f = &Filter{}
+	}
+	queryDB, err := f.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return 0, err
+	}
+	if f.Debug {
+		f.PrintSQLs()
+	}
+	var count int64
+	if err := countQuery(db, f, queryDB).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
 }
 
-// QueryWithFilter 通用查询函数
+// PluckByFilter 按 Filter 的筛选条件、排序和分页查询单列，结果为 V 类型的切片。
+// 有 JOIN 时未指定表名的列按模型的表处理
+func PluckByFilter[T any, V any](db *gorm.DB, f *Filter, column string) ([]V, error) {
+	if !identifierPattern.MatchString(column) {
+		return nil, fmt.Errorf("invalid column %q", column)
+	}
+	queryDB, err := f.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return nil, err
+	}
+	if queryDB, err = f.ApplySortAndPaginationE(queryDB); err != nil {
+		return nil, err
+	}
+	if f.Debug {
+		f.PrintSQLs()
+	}
+	if len(f.Joins) > 0 && !strings.Contains(column, ".") && f.schema != nil {
+		column = f.schema.Table + "." + column
+	}
+	col := quoteIdent(queryDB, column)
+	res := []V{}
+	if err := queryDB.Select(col).Pluck(col, &res).Error; err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// PluckIDs 按 Filter 查询匹配记录的 id
+func PluckIDs[T any](db *gorm.DB, f *Filter) ([]uint, error) {
+	return PluckByFilter[T, uint](db, f, "id")
+}
+
+// 统计总数的查询，去重时对去重后的结果集计数，保证总数与列表一致
+func countQuery(db *gorm.DB, f *Filter, queryDB *gorm.DB) *gorm.DB {
+	switch {
+	case f.CountDistinctColumn != "":
+		// 新会话上设置 Select，避免影响后续的列表查询
+		return queryDB.Session(&gorm.Session{}).Select(fmt.Sprintf("COUNT(DISTINCT %s)", f.CountDistinctColumn))
+	case f.Distinct:
+		return db.Session(&gorm.Session{NewDB: true}).Table("(?) AS distinct_rows", queryDB)
+	}
+	return queryDB
+}
+
+// 使用 Postgres 执行计划估算查询的行数，失败时返回 false
+func estimateCount(queryDB *gorm.DB) (int64, bool) {
+	stmt := queryDB.Session(&gorm.Session{DryRun: true}).Find(nil).Statement
+	rows, err := queryDB.Statement.ConnPool.QueryContext(queryDB.Statement.Context, "EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...)
+	if err != nil {
+		return 0, false
+	}
+	defer rows.Close()
+	var plan string
+	if !rows.Next() || rows.Scan(&plan) != nil {
+		return 0, false
+	}
+	var explain []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if json.Unmarshal([]byte(plan), &explain) != nil || len(explain) == 0 {
+		return 0, false
+	}
+	return int64(explain[0].Plan.Rows), true
+}
+
+// QueryWithFilter 通用查询函数，f.NoPagination 为 true 时返回全部匹配的记录
 func QueryWithFilter[T any](db *gorm.DB, f *Filter) ([]T, error) {
 	var result []T
-	queryDB := f.PaginationQuery(db.Model(new(T)))
-	queryDB = f.ApplySortAndPagination(queryDB)
+	queryDB, err := f.PaginationQueryE(db.Model(new(T)))
+	if err != nil {
+		return nil, err
+	}
+	if queryDB, err = f.ApplySortAndPaginationE(queryDB); err != nil {
+		return nil, err
+	}
 	// SQL日志
 	if f.Debug {
 		f.PrintSQLs()
 	}
 
-	if err := queryDB.Find(&result).Error; err != nil {
+	if err := findPage(f, queryDB, &result); err != nil {
 		return nil, err
 	}
 
 	return result, nil
 }
 
+// 查询本页记录。DeferredJoin 时先按排序和 OFFSET 查出本页主键，再按主键查询完整记录，排序不变
+func findPage[T any](f *Filter, listDB *gorm.DB, result *[]T) error {
+	if !f.deferredJoinable() {
+		return listDB.Find(result).Error
+	}
+	col := quoteIdent(listDB, f.keysetColumn())
+	// 按主键类型接收，避免扫描到模型结构体
+	ids := reflect.New(reflect.SliceOf(f.schema.LookUpField("id").FieldType))
+	if err := listDB.Session(&gorm.Session{}).Select(col).Pluck(col, ids.Interface()).Error; err != nil {
+		return err
+	}
+	n := ids.Elem().Len()
+	f.recordSQL("DEFERRED JOIN", n)
+	if n == 0 {
+		*result = []T{}
+		return nil
+	}
+	return listDB.Session(&gorm.Session{}).Offset(-1).Limit(-1).
+		Where(fmt.Sprintf("%s IN ?", col), ids.Elem().Interface()).
+		Find(result).Error
+}
+
+// 是否可以使用 DeferredJoin：需有 OFFSET，且没有去重、分组
+func (f *Filter) deferredJoinable() bool {
+	switch {
+	case !f.DeferredJoin, f.NoPagination, f.Page <= 1, f.AfterID > 0, f.BeforeID > 0:
+		return false
+	case f.Distinct, len(f.DistinctColumns) > 0, len(f.GroupBy) > 0:
+		return false
+	}
+	return f.schema != nil && f.schema.LookUpField("id") != nil
+}
+
 // SoftDeleteById 通用的根据ID删除记录,   DeletedAt  gorm.DeletedAt `gorm:"column:deleted_at" json:"-"`
 func SoftDeleteById[T any](db *gorm.DB, id uint) error {
 	if id == 0 {
@@ -125,6 +901,141 @@ func DeleteById[T any](db *gorm.DB, id uint) error {
 	return nil
 }
 
+// DeleteByIds 根据ID列表批量设置 is_deleted = 1，返回影响的行数，部分ID不存在时不报错，全部不存在时返回 gorm.ErrRecordNotFound
+func DeleteByIds[T any](db *gorm.DB, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, errors.New("ids cannot be empty")
+	}
+	return affectedOrNotFound(db.Model(new(T)).
+		Where("id IN ?", ids).
+		UpdateColumn("is_deleted", 1))
+}
+
+// SoftDeleteByIds 根据ID列表批量软删除，模型需有 gorm.DeletedAt 字段，返回值同 DeleteByIds
+func SoftDeleteByIds[T any](db *gorm.DB, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, errors.New("ids cannot be empty")
+	}
+	return affectedOrNotFound(db.Where("id IN ?", ids).Delete(new(T)))
+}
+
+// RestoreById 恢复软删除的记录（deleted_at 置为 NULL），记录不存在或未被删除时返回 gorm.ErrRecordNotFound
+func RestoreById[T any](db *gorm.DB, id uint) error {
+	if id == 0 {
+		return errors.New("id cannot be zero")
+	}
+	return checkUpdated(db.Unscoped().Model(new(T)).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		UpdateColumn("deleted_at", nil))
+}
+
+// RestoreFlaggedById 恢复 DeleteById 删除的记录（is_deleted 置为 0），记录不存在或未被删除时返回 gorm.ErrRecordNotFound
+func RestoreFlaggedById[T any](db *gorm.DB, id uint) error {
+	if id == 0 {
+		return errors.New("id cannot be zero")
+	}
+	return checkUpdated(db.Model(new(T)).
+		Where("id = ? AND is_deleted = 1", id).
+		UpdateColumn("is_deleted", 0))
+}
+
+// RestoreByIds 批量恢复软删除的记录，返回恢复的行数，全部不存在或未被删除时返回 gorm.ErrRecordNotFound
+func RestoreByIds[T any](db *gorm.DB, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, errors.New("ids cannot be empty")
+	}
+	return affectedOrNotFound(db.Unscoped().Model(new(T)).
+		Where("id IN ? AND deleted_at IS NOT NULL", ids).
+		UpdateColumn("deleted_at", nil))
+}
+
+// ForceDeleteById 根据ID物理删除记录，包括已软删除的记录，没有匹配的记录时返回 gorm.ErrRecordNotFound
+func ForceDeleteById[T any](db *gorm.DB, id uint) error {
+	if id == 0 {
+		return errors.New("id cannot be zero")
+	}
+	return checkUpdated(db.Unscoped().Where("id = ?", id).Delete(new(T)))
+}
+
+// ForceDeleteByIds 根据ID列表批量物理删除，返回删除的行数，全部不存在时返回 gorm.ErrRecordNotFound
+func ForceDeleteByIds[T any](db *gorm.DB, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, errors.New("ids cannot be empty")
+	}
+	return affectedOrNotFound(db.Unscoped().Where("id IN ?", ids).Delete(new(T)))
+}
+
+// TouchById 把记录的更新时间列设置为当前时间，不修改其他数据，记录不存在时返回 gorm.ErrRecordNotFound。
+// 更新时间列为模型中设置了 autoUpdateTime 的字段，没有时使用 updated_at 列
+func TouchById[T any](db *gorm.DB, id uint) error {
+	if id == 0 {
+		return errors.New("id cannot be zero")
+	}
+	col, now, err := touchValue[T](db)
+	if err != nil {
+		return err
+	}
+	return checkUpdated(db.Model(new(T)).
+		Where("id = ?", id).
+		UpdateColumn(col, now))
+}
+
+// TouchByIds 批量更新记录的更新时间，返回影响的行数，全部不存在时返回 gorm.ErrRecordNotFound
+func TouchByIds[T any](db *gorm.DB, ids []uint) (int64, error) {
+	if len(ids) == 0 {
+		return 0, errors.New("ids cannot be empty")
+	}
+	col, now, err := touchValue[T](db)
+	if err != nil {
+		return 0, err
+	}
+	return affectedOrNotFound(db.Model(new(T)).
+		Where("id IN ?", ids).
+		UpdateColumn(col, now))
+}
+
+// 从模型结构中找到更新时间列，按字段类型生成当前时间（time.Time 或 Unix 秒/毫秒/纳秒）
+func touchValue[T any](db *gorm.DB) (string, interface{}, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return "", nil, err
+	}
+	var field *schema.Field
+	for _, f := range stmt.Schema.Fields {
+		if f.AutoUpdateTime > 0 {
+			field = f
+			break
+		}
+	}
+	if field == nil {
+		field = stmt.Schema.LookUpField("updated_at")
+	}
+	if field == nil || field.DBName == "" {
+		return "", nil, fmt.Errorf("touch: %s has no updated_at column", stmt.Schema.Name)
+	}
+	now := db.NowFunc()
+	switch field.AutoUpdateTime {
+	case schema.UnixNanosecond:
+		return field.DBName, now.UnixNano(), nil
+	case schema.UnixMillisecond:
+		return field.DBName, now.UnixMilli(), nil
+	case schema.UnixSecond:
+		return field.DBName, now.Unix(), nil
+	}
+	return field.DBName, now, nil
+}
+
+// 返回影响的行数，没有影响任何行时返回 gorm.ErrRecordNotFound
+func affectedOrNotFound(result *gorm.DB) (int64, error) {
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return result.RowsAffected, nil
+}
+
 func GetDB[T any](db *gorm.DB) *gorm.DB {
 	return db.Model(new(T))
 }
@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestQueryPageDoesNotModifyFilter(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{Sort: "name", Sortable: []string{"name"}, SkipCount: true}
+	if _, err := QueryPage[item](db, f); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), `ORDER BY "name" ASC,"items"."id" ASC`)
+	if f.StableSort {
+		t.Error("QueryPage should not enable StableSort on the caller's filter")
+	}
+
+	if _, _, _, _, err := QueryWithPagination[item](db, f); err != nil {
+		t.Fatal(err)
+	}
+	assertNotContains(t, rec.last(), `"items"."id" ASC`)
+}
+
+func TestQueryPageKeysetHasNext(t *testing.T) {
+	// 匹配筛选条件的共 10 条，游标之后只剩 3 条
+	db := fakeDB(t, &slowDriver{rows: 3, total: 10})
+	page, err := QueryPage[item](db, &Filter{AfterID: 7, PageSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.HasNext || page.TotalPages != -1 || len(page.Items) != 3 {
+		t.Errorf("last keyset page: has_next=%v total_pages=%d items=%d", page.HasNext, page.TotalPages, len(page.Items))
+	}
+
+	db = fakeDB(t, &slowDriver{rows: 6, total: 10})
+	page, err = QueryPage[item](db, &Filter{AfterID: 1, PageSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !page.HasNext || len(page.Items) != 5 {
+		t.Errorf("keyset page with more rows: has_next=%v items=%d", page.HasNext, len(page.Items))
+	}
+}
+
+func TestUpdateByIdsWithMapRejectsProtected(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	_, err := UpdateByIdsWithMap[item](db, []uint{1, 2}, map[string]interface{}{"id": 5, "name": "x"})
+	if !errors.Is(err, ErrProtectedColumn) {
+		t.Fatalf("want ErrProtectedColumn, got %v", err)
+	}
+	if _, err := UpdateByIdsWithMap[item](db, []uint{1, 2}, nil); !errors.Is(err, ErrEmptyUpdates) {
+		t.Fatalf("want ErrEmptyUpdates, got %v", err)
+	}
+	if sqls := rec.all(); len(sqls) != 0 {
+		t.Errorf("update should not run, got %v", sqls)
+	}
+
+	repo := NewBaseRepository[item](db, WithProtectedColumns("status"))
+	if _, err := repo.UpdateByIds([]uint{1, 2}, map[string]interface{}{"status": 2}); !errors.Is(err, ErrProtectedColumn) {
+		t.Fatalf("want ErrProtectedColumn from repository, got %v", err)
+	}
+}
+
+func TestDistinctCountWithLeftJoin(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{
+		Distinct:        true,
+		DistinctColumns: []string{"items.id", "items.name"},
+		Joins:           []JoinConfig{{Table: "orders", On: "orders.item_id = items.id", JoinType: "left"}},
+		Filters:         map[string]interface{}{"orders.status": "paid"},
+	}
+	distinct := `SELECT DISTINCT items.id,items.name FROM "items" LEFT JOIN "orders" ON orders.item_id = items.id`
+
+	// 总数对去重后的结果集计数，与列表的行数一致
+	if _, err := CountByFilter[item](db, f.Clone()); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), `SELECT count(*) FROM (`+distinct, `) AS distinct_rows`)
+	if _, err := QueryWithFilter[item](db, f.Clone()); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), distinct)
+
+	// 不去重时 JOIN 的每一行都计入总数
+	f.Distinct, f.DistinctColumns = false, nil
+	if _, err := CountByFilter[item](db, f); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), `SELECT count(*) FROM "items" LEFT JOIN "orders"`)
+	assertNotContains(t, rec.last(), "DISTINCT")
+}
+
+func TestCountDistinctColumnWithOneToManyJoin(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{
+		CountDistinctColumn: "items.id",
+		Joins:               []JoinConfig{{Table: "orders", On: "orders.item_id = items.id", JoinType: "left"}},
+	}
+	if _, err := CountByFilter[item](db, f.Clone()); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), `SELECT COUNT(DISTINCT items.id) FROM "items" LEFT JOIN "orders" ON orders.item_id = items.id`)
+
+	// 列表查询不受影响
+	if _, err := QueryWithFilter[item](db, f.Clone()); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), `SELECT "items"."id"`)
+	assertNotContains(t, rec.last(), "COUNT", "DISTINCT")
+}
+
+func TestUpsertByDialect(t *testing.T) {
+	tests := []struct {
+		dialect string
+		update  []string
+		want    string
+	}{
+		{"mysql", []string{"name", "stock"}, "ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`stock`=VALUES(`stock`)"},
+		{"postgres", []string{"name", "stock"}, `ON CONFLICT ("id") DO UPDATE SET "name"="excluded"."name","stock"="excluded"."stock"`},
+		{"postgres", nil, `ON CONFLICT ("id") DO UPDATE SET "updated_at"=`},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, tt.dialect)
+		if err := Upsert[item](db, &item{ID: 1, Name: "a"}, []string{"id"}, tt.update); err != nil {
+			t.Fatal(err)
+		}
+		assertContains(t, rec.last(), tt.want)
+		if tt.update == nil {
+			assertContains(t, rec.last(), `"name"="excluded"."name"`, `"stock"="excluded"."stock"`)
+		}
+	}
+}
+
+func TestUpsertBatchByDialect(t *testing.T) {
+	tests := []struct {
+		dialect, want string
+	}{
+		{"mysql", "ON DUPLICATE KEY UPDATE `stock`=VALUES(`stock`)"},
+		{"postgres", `ON CONFLICT ("id") DO UPDATE SET "stock"="excluded"."stock"`},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, tt.dialect)
+		items := []item{{ID: 1, Stock: 3}, {ID: 2, Stock: 4}}
+		if _, err := UpsertBatch[item](db, items, []string{"id"}, []string{"stock"}); err != nil {
+			t.Fatal(err)
+		}
+		assertContains(t, rec.last(), "INSERT INTO", tt.want)
+	}
+}
+
+func TestUpsertInvalidColumn(t *testing.T) {
+	db, rec := dryRunDB(t, "mysql")
+	if err := Upsert[item](db, &item{ID: 1}, []string{"id"}, []string{"name=1"}); err == nil {
+		t.Fatal("want error for invalid column")
+	}
+	if sql := rec.last(); sql != "" {
+		t.Errorf("insert should not run, got %s", sql)
+	}
+}
+
+func TestWrapNotFound(t *testing.T) {
+	err := wrapNotFound[item](gorm.ErrRecordNotFound)
+	var nf *NotFoundError
+	if !errors.As(err, &nf) || nf.Model != "item" {
+		t.Fatalf("want *NotFoundError for item, got %v", err)
+	}
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("want errors.Is to match ErrNotFound and gorm.ErrRecordNotFound, got %v", err)
+	}
+	other := errors.New("boom")
+	if wrapNotFound[item](other) != other {
+		t.Error("other errors should be returned unchanged")
+	}
+}
+
+func TestFirstOrCreateAssignsWhereAndAttrs(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	where := map[string]interface{}{"name": "a", "status": []interface{}{1, 2}}
+	res, _, err := FirstOrCreate[item](db, where, &item{Stock: 5, Status: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqls := rec.all()
+	if len(sqls) != 2 {
+		t.Fatalf("want query and insert, got %v", sqls)
+	}
+	assertContains(t, sqls[0], `"name" = 'a'`, `"status" IN (1,2)`, `ORDER BY "items"."id" LIMIT 1`)
+	assertContains(t, sqls[1], `INSERT INTO "items"`)
+	// 等值条件和 attrs 的非零字段写入新记录，IN 条件不赋值
+	if res.Name != "a" || res.Stock != 5 || res.Status != 0 {
+		t.Errorf("created record = %+v", res)
+	}
+}
+
+func TestFirstOrCreateInvalidWhere(t *testing.T) {
+	db, _ := dryRunDB(t, "sqlite")
+	if _, _, err := FirstOrCreate[item](db, nil, nil); err == nil {
+		t.Error("want error for empty where")
+	}
+	if _, _, err := FirstOrCreate[item](db, map[string]interface{}{"name;drop": 1}, nil); err == nil {
+		t.Error("want error for invalid column")
+	}
+}
+
+func TestFirstOrCreateExisting(t *testing.T) {
+	rec := &sqlRecorder{}
+	db := tableDB(t, newTableDriver(3, 4), rec)
+	res, created, err := FirstOrCreate[item](db, map[string]interface{}{"name": "0000"}, &item{Stock: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created || res.ID != 1 || res.Stock != 0 {
+		t.Errorf("existing record: created=%v record=%+v", created, res)
+	}
+	if sqls := rec.all(); len(sqls) != 1 {
+		t.Errorf("want only the lookup, got %v", sqls)
+	}
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// 模拟慢查询的驱动：每条语句耗时 latency，COUNT 返回 total（为 0 时返回 rows），列表查询返回 rows 条记录
+type slowDriver struct {
+	latency time.Duration
+	rows    int
+	total   int
+}
+
+func (d *slowDriver) Open(string) (driver.Conn, error) { return &slowConn{d: d}, nil }
+
+type slowConn struct {
+	d *slowDriver
+}
+
+func (c *slowConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *slowConn) Close() error                        { return nil }
+func (c *slowConn) Begin() (driver.Tx, error)           { return slowTx{}, nil }
+
+func (c *slowConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	select {
+	case <-time.After(c.d.latency):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if strings.Contains(strings.ToLower(query), "count(") {
+		total := c.d.total
+		if total == 0 {
+			total = c.d.rows
+		}
+		return &slowRows{cols: []string{"count"}, values: [][]driver.Value{{int64(total)}}}, nil
+	}
+	values := make([][]driver.Value, c.d.rows)
+	for i := range values {
+		values[i] = []driver.Value{int64(i + 1), "name"}
+	}
+	return &slowRows{cols: []string{"id", "name"}, values: values}, nil
+}
+
+type slowTx struct{}
+
+func (slowTx) Commit() error   { return nil }
+func (slowTx) Rollback() error { return nil }
+
+type slowRows struct {
+	cols   []string
+	values [][]driver.Value
+	i      int
+}
+
+func (r *slowRows) Columns() []string { return r.cols }
+func (r *slowRows) Close() error      { return nil }
+
+func (r *slowRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.i])
+	r.i++
+	return nil
+}
+
+// 每条语句耗时 latency 的连接
+func slowDB(t testing.TB, latency time.Duration) *gorm.DB {
+	t.Helper()
+	return fakeDB(t, &slowDriver{latency: latency, rows: 10})
+}
+
+// 使用模拟驱动 d 的连接
+func fakeDB(t testing.TB, d *slowDriver) *gorm.DB {
+	t.Helper()
+	pool := sql.OpenDB(slowConnector{d})
+	db, err := gorm.Open(testDialector{name: "sqlite", pool: pool}, &gorm.Config{Logger: logger.Discard, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+type slowConnector struct {
+	d *slowDriver
+}
+
+func (c slowConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c slowConnector) Driver() driver.Driver                        { return c.d }
+
+func TestParallelPagination(t *testing.T) {
+	db := slowDB(t, time.Millisecond)
+	items, total, _, _, err := QueryWithPagination[item](db, &Filter{Parallel: true, PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 10 || len(items) != 10 {
+		t.Errorf("want 10 items and total 10, got %d items, total %d", len(items), total)
+	}
+}
+
+func TestParallelFallsBackInTransaction(t *testing.T) {
+	db := slowDB(t, time.Millisecond)
+	if inTransaction(db) {
+		t.Fatal("plain connection pool reported as transaction")
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if !inTransaction(tx) {
+			t.Error("transaction not detected")
+		}
+		_, total, _, _, err := QueryWithPagination[item](tx, &Filter{Parallel: true, PageSize: 10})
+		if err == nil && total != 10 {
+			t.Errorf("want total 10, got %d", total)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 每条语句 5ms 时，Parallel 的耗时约为顺序执行的一半
+func BenchmarkQueryWithPagination(b *testing.B) {
+	db := slowDB(b, 5*time.Millisecond)
+	for _, parallel := range []bool{false, true} {
+		name := "sequential"
+		if parallel {
+			name = "parallel"
+		}
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, _, _, _, err := QueryWithPagination[item](db, &Filter{Parallel: parallel, PageSize: 10}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -2,19 +2,47 @@ package repository
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
+// 默认 and/or 条件组最大嵌套层数
+const defaultMaxGroupDepth = 5
+
+// QueryStr 默认最多可包含的条件数
+const defaultMaxConditions = 50
+
+// QueryStr 默认最大字节数
+const defaultMaxQueryStrBytes = 64 << 10
+
+// QueryStr 默认最大 JSON 嵌套层数（对象和数组各计一层）
+const defaultMaxDepth = 20
+
+// 多列 IN 每条语句片段的最大元组数，超出时拆分为多个片段 OR 连接
+const tupleInChunkSize = 500
+
+// DefaultAlwaysSortable 未设置 Filter.AlwaysSortable 时始终可排序的字段
+var DefaultAlwaysSortable = []string{"id", "created_at", "updated_at"}
+
 // Filter 筛选结构体
 type Filter struct {
 	Filterable []string               //可供筛选的字段
 	QueryStr   string                 //接口url传的query字符串
 	Filters    map[string]interface{} //业务逻辑中使用
-	Sortable   []string               //可供排序的字段
-	Sort       string
+	Sortable   []string               //可供排序的字段，支持 "表名.字段名" 和 "表名.*"
+	Sort       string                 //排序，逗号分隔，如 "-created_at,name asc"，重复字段只保留第一次出现
 	Page       int
 	PageSize   int
 	Unscoped   bool         //是否包含软删除的记录
@@ -22,6 +50,92 @@ type Filter struct {
 	sqlRecords []string
 	Debug      bool
 	finalSQL   string
+	errs       []FilterError
+
+	MaxGroupDepth    int               //and/or 条件组最大嵌套层数，0 使用默认值
+	Location         *time.Location    //日期条件使用的时区，不带时区的日期按该时区解析后转为 UTC 绑定；nil 时不转换，date_eq 等使用服务器本地时区
+	Now              func() time.Time  //相对日期的当前时间，nil 使用 time.Now，便于测试固定时间
+	FieldTypes       map[string]string //字段类型提示，"time" 表示字符串值需解析为 time.Time 再绑定
+	IgnoreZero       bool              //跳过值为空字符串或 nil 的条件
+	IgnoreZeroNumber bool              //跳过值为数字 0 的条件
+
+	RawConditions []RawCondition //原生 SQL 条件，仅供 Go 代码使用
+	exprs         map[string]string
+
+	Exists []ExistsCondition //EXISTS / NOT EXISTS 子查询条件
+
+	InSubqueries  []InSubquery //IN 子查询条件
+	subqueryConds map[string]map[string]interface{}
+
+	Distinct            bool     //去重，JOIN 导致重复行时使用
+	DistinctColumns     []string //去重的列，为空时按模型全部列去重
+	CountDistinctColumn string   //统计总数时按该列去重，如 "users.id"，不影响列表查询
+
+	Fields     []string //查询的列，支持 "表名.字段名"
+	OmitFields []string //排除的列，校验规则同 Fields
+	Selectable []string //可供查询的列，为空时使用 Filterable + Sortable
+
+	GroupBy []string //分组列，仅 AggregateQuery 使用，需可筛选
+	schema  *schema.Schema
+
+	FieldAliases map[string]string //接口字段名到数据库列的映射，如 "createdAt": "created_at"，在白名单校验之前转换
+
+	Search       string   //全局搜索关键字
+	SearchFields []string //全局搜索匹配的列，需可筛选，各列之间 OR
+	SearchMode   string   //"like"（默认）或 "match"，match 在不支持全文检索的数据库上回退为 like
+
+	MaxConditions    int //QueryStr 最多可包含的条件数（含嵌套的运算符），0 使用默认值，负数表示不限制
+	MaxQueryStrBytes int //QueryStr 最大字节数，0 使用默认值 64KB，负数表示不限制
+	MaxDepth         int //QueryStr 最大 JSON 嵌套层数，0 使用默认值，负数表示不限制
+
+	Strict bool //严格模式：不可筛选/不可排序的字段、未知运算符不再静默忽略，汇总后一并返回错误
+
+	QuerySyntax string //QueryStr 的语法，默认 JSON，"rsql" 使用 ParseRSQL 解析
+
+	AlwaysSortable []string //始终可排序的字段，nil 使用 DefaultAlwaysSortable，空切片表示不放行任何字段
+
+	AfterID  uint //按 id 游标分页，查询 id 大于该值的记录，不使用 OFFSET，不能与 Page > 1 同时使用
+	BeforeID uint //按 id 游标分页，查询 id 小于该值的记录，按 id 倒序
+
+	MaxPageSize int //每页最大条数，0 使用全局默认值（见 SetDefaultMaxPageSize），超出时截断，严格模式下报错
+
+	NoPagination bool //不分页，返回全部匹配的记录，仍然排序；仅供 Go 代码中的可信查询（如后台任务）使用
+
+	SkipCount bool //不统计总数（返回 -1），多取一条记录判断是否有下一页
+
+	EstimateCount bool //Postgres 上按执行计划估算总数，其他数据库仍执行 COUNT
+
+	DeferredJoin bool //深分页时先按 OFFSET 只查询主键，再按主键查询完整记录
+
+	Parallel bool //并发执行 COUNT 和列表查询，事务中顺序执行
+
+	MaxOffset int //最大偏移量，超出时返回 ErrPageOutOfRange，0 使用全局默认值，负数不限制
+
+	StableSort       bool   //排序中没有唯一列时追加 TiebreakerColumn，保证翻页稳定
+	TiebreakerColumn string //StableSort 追加的唯一列，默认为模型主键
+
+	RandomOrder bool  //随机排序，仅供 Go 代码使用；客户端需通过 Sort "random" 触发，且 Sortable 中显式包含 "random"
+	RandomSeed  int64 //随机排序的种子，0 表示不指定；相同种子在 MySQL、Postgres 上得到相同的顺序
+
+	Cursor     string //QueryWithCursor 使用的游标，为空时查询第一页
+	prevCursor string
+
+	SortExprs map[string]string //命名排序表达式，如 "status_rank": "FIELD(status, 'active','pending','closed')"，Sort 中可直接使用名称
+
+	Collation string //排序修饰 ":ci" 在 MySQL 上使用的排序规则（如 utf8mb4_general_ci），为空或其他数据库时使用 LOWER(列)
+
+	SortAliases map[string]string //对外的排序名到列的映射，如 "ownerName": "users.name"，按对外名称校验 Sortable，ORDER BY 使用映射的列
+
+	BaseConditions map[string]interface{} //始终应用的基础条件（如 is_deleted = 0），写法同 Filters，来自可信代码，不受 Filterable 限制
+	applyingBase   bool
+
+	rejectIgnored bool //按 IgnoreZero 跳过的条件记为错误，批量更新、删除使用，避免跳过条件扩大影响范围
+}
+
+// RawCondition 原生 SQL 条件，参数通过占位符绑定
+type RawCondition struct {
+	SQL  string
+	Args []interface{}
 }
 
 // JoinConfig JOIN 配置结构
@@ -31,11 +145,35 @@ type JoinConfig struct {
 	JoinType string // "left" 或 "inner"
 }
 
-// PaginationQuery 主入口
+// PaginationQuery 主入口，db 通过 Model 指定了模型时（如 QueryWithPagination[T]），条件值按模型字段类型转换。
+// 为兼容保留原有行为：有误的条件直接跳过，不返回错误；需要获取错误时使用 PaginationQueryE
 func (f *Filter) PaginationQuery(db *gorm.DB) *gorm.DB {
+	db, _ = f.paginationQuery(db)
+	return db
+}
+
+// PaginationQueryE 同 PaginationQuery，同时返回 QueryStr 解析、字段和运算符校验等错误，
+// 错误也挂在返回的 *gorm.DB 上，避免执行残缺的查询
+func (f *Filter) PaginationQueryE(db *gorm.DB) (*gorm.DB, error) {
+	db, err := f.paginationQuery(db)
+	if err != nil {
+		_ = db.AddError(err)
+	}
+	return db, err
+}
+
+// 构建筛选条件，返回汇总的错误，不挂到 db 上
+func (f *Filter) paginationQuery(db *gorm.DB) (*gorm.DB, error) {
 	if f.Debug {
 		f.sqlRecords = []string{}
 	}
+	f.errs = nil
+	f.subqueryConds = nil
+	// 解析模型结构，用于按列类型转换条件值
+	f.schema = nil
+	if db.Statement.Model != nil && db.Statement.Parse(db.Statement.Model) == nil {
+		f.schema = db.Statement.Schema
+	}
 
 	// 先处理 Unscoped（软删除）
 	if f.Unscoped {
@@ -43,193 +181,1734 @@ func (f *Filter) PaginationQuery(db *gorm.DB) *gorm.DB {
 		f.recordSQL("UNSCOPED", "include soft-deleted records")
 	}
 
+	// 去重
+	if f.Distinct {
+		args := make([]interface{}, len(f.DistinctColumns))
+		for i, c := range f.DistinctColumns {
+			args[i] = c
+		}
+		db = db.Distinct(args...)
+		f.recordSQL("DISTINCT", f.DistinctColumns)
+	}
+	if f.CountDistinctColumn != "" && !identifierPattern.MatchString(f.CountDistinctColumn) {
+		f.addError(f.CountDistinctColumn, "count_distinct", "invalid column name")
+	}
+
+	// 查询的列，设置了 DistinctColumns 时以其为准
+	if len(f.Fields) > 0 && len(f.DistinctColumns) == 0 {
+		if fields := f.selectableFields(f.Fields, "SELECT"); len(fields) > 0 {
+			db = db.Select(fields)
+			f.recordSQL("SELECT", fields)
+		}
+	}
+	if len(f.OmitFields) > 0 {
+		if fields := f.selectableFields(f.OmitFields, "OMIT"); len(fields) > 0 {
+			db = db.Omit(fields...)
+			f.recordSQL("OMIT", fields)
+		}
+	}
+
 	// 执行 JOIN
 	if len(f.Joins) > 0 {
 		for _, j := range f.Joins {
+			table := quoteIdent(db, j.Table)
 			switch strings.ToLower(j.JoinType) {
 			case "left":
-				db = db.Joins(fmt.Sprintf("LEFT JOIN %s ON %s", j.Table, j.On))
+				db = db.Joins(fmt.Sprintf("LEFT JOIN %s ON %s", table, j.On))
 				f.recordSQL(fmt.Sprintf("LEFT JOIN %s ON %s", j.Table, j.On), nil)
 			default:
-				db = db.Joins(fmt.Sprintf("INNER JOIN %s ON %s", j.Table, j.On))
+				db = db.Joins(fmt.Sprintf("INNER JOIN %s ON %s", table, j.On))
 				f.recordSQL(fmt.Sprintf("INNER JOIN %s ON %s", j.Table, j.On), nil)
 			}
 		}
 	}
 
+	// 基础条件，先于其他条件应用
+	if len(f.BaseConditions) > 0 {
+		f.applyingBase = true
+		db = f.applyQueryConditions(db, f.BaseConditions, 0)
+		f.applyingBase = false
+	}
 	// Filters条件
 	if len(f.Filters) > 0 {
-		db = f.applyQueryConditions(db, f.Filters)
+		db = f.applyQueryConditions(db, f.Filters, 0)
 	}
 	// 动态条件
 	if f.QueryStr != "" {
-		var queryMap map[string]interface{}
-		if err := json.Unmarshal([]byte(f.QueryStr), &queryMap); err == nil {
-			db = f.applyQueryConditions(db, queryMap)
+		if queryMap, err := f.parseQueryStr(); err != nil {
+			f.addError("query", "", err.Error())
+		} else if max := f.maxConditions(); max >= 0 && countConditions(queryMap) > max {
+			// 条件数超限时不构建查询
+			f.addError("query", "", fmt.Sprintf("too many conditions, max %d", max))
+		} else {
+			db = f.applyQueryConditions(db, queryMap, 0)
+		}
+	}
+	// 全局搜索
+	if f.Search != "" && len(f.SearchFields) > 0 {
+		db = f.applySearch(db)
+	}
+	// IN 子查询
+	for _, sq := range f.InSubqueries {
+		db = f.applyInSubquery(db, sq)
+	}
+	for name := range f.subqueryConds {
+		if !f.hasInSubquery(name) {
+			f.addError("_in_subquery", name, "subquery is not configured")
 		}
 	}
+	// EXISTS 子查询
+	for _, ec := range f.Exists {
+		db = f.applyExists(db, ec)
+	}
+	// 原生条件
+	for _, rc := range f.RawConditions {
+		db = db.Where(rc.SQL, rc.Args...)
+		f.recordSQL(fmt.Sprintf("RAW %s", rc.SQL), rc.Args)
+	}
 
-	return db
+	return db, f.err()
+}
+
+// 按 QuerySyntax 解析 QueryStr
+func (f *Filter) parseQueryStr() (map[string]interface{}, error) {
+	// 解码前先检查长度，避免超大的输入消耗内存
+	if max := limit(f.MaxQueryStrBytes, defaultMaxQueryStrBytes); max >= 0 && len(f.QueryStr) > max {
+		return nil, fmt.Errorf("query string exceeds %d bytes", max)
+	}
+	if f.QuerySyntax == "rsql" {
+		return ParseRSQL(f.QueryStr)
+	}
+	if max := limit(f.MaxDepth, defaultMaxDepth); max >= 0 {
+		if err := checkJSONDepth(f.QueryStr, max); err != nil {
+			return nil, err
+		}
+	}
+	var queryMap map[string]interface{}
+	if err := json.Unmarshal([]byte(f.QueryStr), &queryMap); err != nil {
+		return nil, fmt.Errorf("invalid json: %v", err)
+	}
+	return queryMap, nil
+}
+
+// 逐个读取 token 检查嵌套层数，超限时立即返回，不构建中间结果
+func checkJSONDepth(s string, max int) error {
+	dec := json.NewDecoder(strings.NewReader(s))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid json: %v", err)
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > max {
+				return fmt.Errorf("nesting exceeds max depth %d", max)
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// 取限制值：0 使用默认值，负数表示不限制
+func limit(v, def int) int {
+	if v != 0 {
+		return v
+	}
+	return def
+}
+
+// RegisterExpr 注册命名表达式模板，QueryStr 只能通过
+// {"_expr": {"name": "trimmed_name_eq", "args": ["x"]}} 引用已注册的模板，不能传入任意 SQL
+func (f *Filter) RegisterExpr(name, sql string) {
+	if f.exprs == nil {
+		f.exprs = make(map[string]string)
+	}
+	f.exprs[name] = sql
+}
+
+// ExistsCondition EXISTS 子查询条件
+type ExistsCondition struct {
+	Table      string                 // 子查询的表，例如 "orders"
+	On         string                 // 关联条件，例如 "orders.user_id = users.id"
+	Conditions map[string]interface{} // 子查询内的筛选条件，写法同 Filters
+	Filterable []string               // 子查询内可供筛选的字段
+	Negate     bool                   // 为 true 时生成 NOT EXISTS
+}
+
+// InSubquery IN 子查询条件，生成 Column IN (SELECT SelectColumn FROM Table WHERE ...)
+// 表和列只能在 Go 代码中配置，QueryStr 只能通过 {"_in_subquery": {"<Name>": {...}}} 补充子查询内的条件值
+type InSubquery struct {
+	Name         string                 // 供 QueryStr 引用的名称
+	Column       string                 // 外层列，例如 "users.id"
+	Table        string                 // 子查询的表，例如 "memberships"
+	SelectColumn string                 // 子查询选择的列，例如 "user_id"
+	Conditions   map[string]interface{} // 子查询内的筛选条件，写法同 Filters
+	Filterable   []string               // QueryStr 补充的条件可筛选的字段，为空时不接受补充的条件
 }
 
 // ================== 内部函数 ==================
 
-// 应用查询条件
-func (f *Filter) applyQueryConditions(db *gorm.DB, conditions map[string]interface{}) *gorm.DB {
+// 应用查询条件，depth 为当前所在条件组的嵌套层数
+func (f *Filter) applyQueryConditions(db *gorm.DB, conditions map[string]interface{}, depth int) *gorm.DB {
 	for field, value := range conditions {
-		// 允许 "表名.字段名"
-		if !f.isFilterable(field) {
+		// 兼容 Mongo 风格的 $and/$or
+		if strings.HasPrefix(field, "$") {
+			if field != "$and" && field != "$or" {
+				f.addError(field, "", "unknown operator")
+				continue
+			}
+			field = field[1:]
+		}
+		// 保留字段 and/or/not：值为条件 map 数组（not 也可为单个 map），可递归嵌套
+		if field == "and" || field == "or" || field == "not" {
+			db = f.applyGroup(db, field, value, depth+1)
+			continue
+		}
+		// 保留字段 _in_subquery：为已配置的 IN 子查询补充条件，仅允许出现在顶层
+		if field == "_in_subquery" {
+			f.collectSubqueryConds(value, depth)
+			continue
+		}
+		// 保留字段 _tuple_in：多列 IN，{"fields": ["a", "b"], "values": [[1, "x"], [2, "y"]]}
+		if field == "_tuple_in" {
+			db = f.applyTupleIn(db, value)
+			continue
+		}
+		// 保留字段 _expr：引用已注册的命名表达式
+		if field == "_expr" {
+			db = f.applyNamedExprs(db, value)
+			continue
+		}
+		// 字段名会拼入 SQL，无论是否配置白名单都必须是合法标识符
+		if !f.safeField(field) {
+			f.addError(field, "", "invalid field name")
 			continue
 		}
+		field = f.resolveAlias(field)
+		// 允许 "表名.字段名"，JSON 路径按所属列判断，逗号分隔的多列逐个判断
+		if !f.applyingBase && !f.isFilterableColumns(jsonColumn(field)) {
+			f.reject(field, "", "field is not filterable")
+			continue
+		}
+		if strings.Contains(field, ",") && !onlyMatch(value) {
+			f.addError(field, "", "multiple columns are only supported by match")
+			continue
+		}
+		if f.isIgnored(value) {
+			if f.rejectIgnored {
+				f.addError(field, "", "empty value is not allowed")
+				continue
+			}
+			f.recordSQL(fmt.Sprintf("SKIP ZERO %s", field), value)
+			continue
+		}
+		column, err := f.columnExpr(db, field)
+		if err != nil {
+			f.addError(field, "", err.Error())
+			continue
+		}
+		if _, ok := value.(map[string]interface{}); !ok {
+			if value, err = f.coerceValue(field, value); err != nil {
+				f.addError(field, "", err.Error())
+				continue
+			}
+		}
 		switch v := value.(type) {
-		case string, int, float64, bool:
-			db = db.Where(fmt.Sprintf("%s = ?", field), v)
+		case nil:
+			// JSON null 按 IS NULL 处理
+			db = db.Where(fmt.Sprintf("%s IS NULL", column))
+			f.recordSQL(fmt.Sprintf("IS_NULL %s", field), nil)
+		case string, int, int64, float64, bool, time.Time:
+			db = db.Where(fmt.Sprintf("%s = ?", column), v)
 			f.recordSQL(fmt.Sprintf("EQ %s", field), v)
-		case []interface{}:
-			db = db.Where(fmt.Sprintf("%s IN (?)", field), v)
-			f.recordSQL(fmt.Sprintf("IN %s", field), v)
-		case []string:
-			db = db.Where(fmt.Sprintf("%s IN (?)", field), v)
-			f.recordSQL(fmt.Sprintf("IN %s", field), v)
+		case []interface{}, []string:
+			db = f.applyIn(db, field, column, v)
 		case map[string]interface{}:
-			db = f.applyComplexCondition(db, field, v)
+			db = f.applyComplexCondition(db, field, column, v)
 		}
 	}
 	return db
 }
 
-// 应用复杂条件（如 like、gt、between）
-func (f *Filter) applyComplexCondition(db *gorm.DB, field string, conds map[string]interface{}) *gorm.DB {
-	for op, value := range conds {
-		switch op {
-		case "eq":
-			db = db.Where(fmt.Sprintf("%s = ?", field), value)
-			f.recordSQL(fmt.Sprintf("EQ %s", field), value)
-		case "neq":
-			db = db.Where(fmt.Sprintf("%s != ?", field), value)
-			f.recordSQL(fmt.Sprintf("NEQ %s", field), value)
-		case "gt":
-			db = db.Where(fmt.Sprintf("%s > ?", field), value)
-			f.recordSQL(fmt.Sprintf("GT %s", field), value)
-		case "gte":
-			db = db.Where(fmt.Sprintf("%s >= ?", field), value)
-			f.recordSQL(fmt.Sprintf("GTE %s", field), value)
-		case "lt":
-			db = db.Where(fmt.Sprintf("%s < ?", field), value)
-			f.recordSQL(fmt.Sprintf("LT %s", field), value)
-		case "lte":
-			db = db.Where(fmt.Sprintf("%s <= ?", field), value)
-			f.recordSQL(fmt.Sprintf("LTE %s", field), value)
-		case "like":
-			db = db.Where(fmt.Sprintf("%s LIKE ?", field), fmt.Sprintf("%v", value))
-			f.recordSQL(fmt.Sprintf("LIKE %s", field), value)
-		case "in":
-			db = db.Where(fmt.Sprintf("%s IN (?)", field), value)
-			f.recordSQL(fmt.Sprintf("IN %s", field), value)
-		case "between":
-			if arr, ok := value.([]interface{}); ok && len(arr) == 2 {
-				db = db.Where(fmt.Sprintf("%s BETWEEN ? AND ?", field), arr[0], arr[1])
-				f.recordSQL(fmt.Sprintf("BETWEEN %s", field), arr)
-			}
+// 应用 and/or/not 条件组，组内各条件按 logic 连接（not 组内 AND 后整体取反），整体与同级其他条件 AND
+func (f *Filter) applyGroup(db *gorm.DB, logic string, value interface{}, depth int) *gorm.DB {
+	if max := f.maxGroupDepth(); depth > max {
+		f.addError(logic, "", fmt.Sprintf("nesting exceeds max depth %d", max))
+		return db
+	}
+	if m, ok := value.(map[string]interface{}); ok && logic == "not" {
+		value = []interface{}{m}
+	}
+	groups, ok := conditionMaps(value)
+	if !ok {
+		f.addError(logic, "", "requires an array of condition objects")
+		return db
+	}
+	var group *gorm.DB
+	f.recordSQL(fmt.Sprintf("%s GROUP BEGIN", strings.ToUpper(logic)), depth)
+	for _, m := range groups {
+		sub := f.applyQueryConditions(newSession(db), m, depth)
+		// 分支内条件全部被过滤时跳过该分支
+		if !hasWhere(sub) {
+			continue
 		}
+		switch {
+		case group == nil:
+			group = newSession(db).Where(sub)
+		case logic == "or":
+			group = group.Or(sub)
+		default:
+			group = group.Where(sub)
+		}
+	}
+	f.recordSQL(fmt.Sprintf("%s GROUP END", strings.ToUpper(logic)), depth)
+	switch {
+	case group == nil:
+	case logic == "not":
+		db = db.Not(group)
+	default:
+		db = db.Where(group)
 	}
 	return db
 }
 
-// ApplySortAndPagination 排序分页
-func (f *Filter) ApplySortAndPagination(db *gorm.DB) *gorm.DB {
-	// 排序
-	if f.Sort != "" {
-		for _, s := range strings.Split(f.Sort, ",") {
-			s = strings.TrimSpace(s)
-			if s == "" {
-				continue
-			}
-			order := "ASC"
-			field := s
-			if strings.HasPrefix(s, "-") {
-				order = "DESC"
-				field = strings.TrimPrefix(s, "-")
-			}
-			if f.isSortable(field) {
-				db = db.Order(fmt.Sprintf("%s %s", field, order))
-				f.recordSQL(fmt.Sprintf("ORDER %s %s", field, order), nil)
+func (f *Filter) maxConditions() int {
+	if f.MaxConditions != 0 {
+		return f.MaxConditions
+	}
+	return defaultMaxConditions
+}
+
+// 统计条件数量，每个运算符计一个，条件组递归统计
+func countConditions(value interface{}) int {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		n := 0
+		for _, item := range v {
+			n += countConditions(item)
+		}
+		return n
+	case []interface{}:
+		if maps, ok := conditionMaps(v); ok && len(maps) > 0 {
+			n := 0
+			for _, m := range maps {
+				n += countConditions(m)
 			}
+			return n
 		}
 	}
+	return 1
+}
 
-	// 分页
-	if f.Page <= 0 {
-		f.Page = 1
-	}
-	if f.PageSize <= 0 {
-		f.PageSize = 10
+func (f *Filter) maxGroupDepth() int {
+	if f.MaxGroupDepth > 0 {
+		return f.MaxGroupDepth
 	}
-	if f.PageSize > 500 {
-		f.PageSize = 500
+	return defaultMaxGroupDepth
+}
+
+// 应用命名表达式，值可以是单个 {"name", "args"} 对象或其数组
+func (f *Filter) applyNamedExprs(db *gorm.DB, value interface{}) *gorm.DB {
+	items, ok := conditionMaps(value)
+	if !ok {
+		m, isMap := value.(map[string]interface{})
+		if !isMap {
+			f.addError("_expr", "", "requires an object with name and args")
+			return db
+		}
+		items = []map[string]interface{}{m}
 	}
-	offset := (f.Page - 1) * f.PageSize
-	db = db.Offset(offset).Limit(f.PageSize)
-	f.recordSQL("Pagination", map[string]int{"page": f.Page, "pageSize": f.PageSize})
-	if f.Debug {
-		sql := db.Session(&gorm.Session{DryRun: true}).ToSQL(func(tx *gorm.DB) *gorm.DB {
-			return tx.Find(nil)
-		})
-		f.finalSQL = sql
+	for _, item := range items {
+		name, _ := item["name"].(string)
+		sql, ok := f.exprs[name]
+		if !ok {
+			f.addError("_expr", name, "expression is not registered")
+			continue
+		}
+		var args []interface{}
+		if raw, exists := item["args"]; exists {
+			if args, ok = raw.([]interface{}); !ok {
+				f.addError("_expr", name, "args must be an array")
+				continue
+			}
+		}
+		if n := strings.Count(sql, "?"); n != len(args) {
+			f.addError("_expr", name, fmt.Sprintf("expects %d args, got %d", n, len(args)))
+			continue
+		}
+		db = db.Where(sql, args...)
+		f.recordSQL(fmt.Sprintf("EXPR %s", name), args)
 	}
 	return db
 }
 
-// 记录调试 SQL
-func (f *Filter) recordSQL(desc string, val interface{}) {
-	if !f.Debug {
+// 暂存 _in_subquery 中按名称提供的子查询条件
+func (f *Filter) collectSubqueryConds(value interface{}, depth int) {
+	if depth > 0 {
+		f.addError("_in_subquery", "", "only allowed at top level")
 		return
 	}
-	f.sqlRecords = append(f.sqlRecords, fmt.Sprintf("[%s] | args: %v", desc, val))
-}
-
-// PrintSQLs 打印调试信息
-func (f *Filter) PrintSQLs() {
-	fmt.Println("=== Generated SQL Statements ===")
-	for i, s := range f.sqlRecords {
-		fmt.Printf("%d. %s\n", i+1, s)
+	entries, ok := value.(map[string]interface{})
+	if !ok {
+		f.addError("_in_subquery", "", "requires an object keyed by subquery name")
+		return
 	}
-	if f.finalSQL != "" {
-		fmt.Println("---------------------------------")
-		fmt.Printf("[Final SQL Preview]\n%s\n", f.finalSQL)
+	if f.subqueryConds == nil {
+		f.subqueryConds = make(map[string]map[string]interface{})
+	}
+	for name, raw := range entries {
+		conds, ok := raw.(map[string]interface{})
+		if !ok {
+			f.addError("_in_subquery", name, "requires a condition object")
+			continue
+		}
+		f.subqueryConds[name] = conds
 	}
-	fmt.Println("=================================")
 }
 
-func (f *Filter) isFilterable(field string) bool {
-	if len(f.Filterable) == 0 {
-		return true
-	}
-	for _, w := range f.Filterable {
-		if w == field {
+func (f *Filter) hasInSubquery(name string) bool {
+	for _, sq := range f.InSubqueries {
+		if sq.Name != "" && sq.Name == name {
 			return true
 		}
 	}
 	return false
 }
 
-func (f *Filter) isSortable(field string) bool {
-	if strings.TrimSpace(field) == "" {
-		return false
+// 应用 IN 子查询
+func (f *Filter) applyInSubquery(db *gorm.DB, sq InSubquery) *gorm.DB {
+	sub := newSession(db).Table(sq.Table).Select(sq.SelectColumn)
+	f.recordSQL(fmt.Sprintf("IN_SUBQUERY %s IN %s.%s", sq.Column, sq.Table, sq.SelectColumn), nil)
+	// Conditions 来自 Go 代码，不受 Filterable 限制
+	sub = f.applyInnerConditions(sub, nil, sq.Conditions)
+	if sq.Name != "" {
+		if conds, ok := f.subqueryConds[sq.Name]; ok {
+			// 补充的条件来自客户端，没有配置可筛选字段时不接受，避免探测子查询表中的其他列
+			if len(sq.Filterable) == 0 {
+				f.addError("_in_subquery", sq.Name, "subquery has no filterable fields")
+			} else {
+				sub = f.applyInnerConditions(sub, sq.Filterable, conds)
+			}
+		}
 	}
-	// 特判：id 字段总是可排序的
-	if field == "id" || field == "created_at" || field == "updated_at" {
-		return true
+	return db.Where(fmt.Sprintf("%s IN (?)", sq.Column), sub)
+}
+
+// 关联条件只允许 "表.列 = 表.列" 形式
+var correlationPattern = regexp.MustCompile(`^\s*[A-Za-z_][A-Za-z0-9_]*\.[A-Za-z_][A-Za-z0-9_]*\s*=\s*[A-Za-z_][A-Za-z0-9_]*\.[A-Za-z_][A-Za-z0-9_]*\s*$`)
+
+// 应用 EXISTS / NOT EXISTS 子查询
+func (f *Filter) applyExists(db *gorm.DB, ec ExistsCondition) *gorm.DB {
+	keyword := "EXISTS"
+	if ec.Negate {
+		keyword = "NOT EXISTS"
+	}
+	if !correlationPattern.MatchString(ec.On) {
+		f.addError(ec.Table, strings.ToLower(keyword), fmt.Sprintf("invalid correlation %q", ec.On))
+		return db
 	}
+	sub := newSession(db).Table(ec.Table).Select("1").Where(ec.On)
+	f.recordSQL(fmt.Sprintf("%s %s ON %s", keyword, ec.Table, ec.On), nil)
+	sub = f.applyInnerConditions(sub, ec.Filterable, ec.Conditions)
+	return db.Where(keyword+" (?)", sub)
+}
 
-	if len(f.Sortable) == 0 {
-		return false
+// 在子查询中应用条件，使用子查询自己的可筛选字段，调试记录和错误汇总到当前 Filter
+func (f *Filter) applyInnerConditions(sub *gorm.DB, filterable []string, conditions map[string]interface{}) *gorm.DB {
+	inner := &Filter{
+		Filterable:       filterable,
+		Debug:            f.Debug,
+		MaxGroupDepth:    f.MaxGroupDepth,
+		Location:         f.Location,
+		Now:              f.Now,
+		FieldTypes:       f.FieldTypes,
+		IgnoreZero:       f.IgnoreZero,
+		IgnoreZeroNumber: f.IgnoreZeroNumber,
 	}
+	sub = inner.applyQueryConditions(sub, conditions, 0)
+	f.sqlRecords = append(f.sqlRecords, inner.sqlRecords...)
+	f.errs = append(f.errs, inner.errs...)
+	return sub
+}
 
-	for _, w := range f.Sortable {
-		if w == field {
-			return true
+// 应用多列 IN，MySQL/Postgres 使用 (a, b) IN ((?, ?), ...)，其他方言展开为 OR 连接的 AND 条件
+func (f *Filter) applyTupleIn(db *gorm.DB, value interface{}) *gorm.DB {
+	spec, ok := value.(map[string]interface{})
+	if !ok {
+		f.addError("_tuple_in", "", "requires an object with fields and values")
+		return db
+	}
+	rawFields, _ := spec["fields"].([]interface{})
+	rawValues, _ := spec["values"].([]interface{})
+	if len(rawFields) == 0 {
+		f.addError("_tuple_in", "", "fields cannot be empty")
+		return db
+	}
+	fields := make([]string, len(rawFields))
+	for i, rf := range rawFields {
+		name, _ := rf.(string)
+		name = f.resolveAlias(name)
+		if !identifierPattern.MatchString(name) || !f.isFilterable(name) {
+			f.addError("_tuple_in", name, "field is not filterable")
+			return db
 		}
+		fields[i] = name
 	}
-	return false
+	tuples := make([][]interface{}, len(rawValues))
+	for i, rv := range rawValues {
+		tuple, ok := rv.([]interface{})
+		if !ok || len(tuple) != len(fields) {
+			f.addError("_tuple_in", "", fmt.Sprintf("value %d must be an array of %d elements", i, len(fields)))
+			return db
+		}
+		coerced := make([]interface{}, len(tuple))
+		for j, item := range tuple {
+			c, err := f.coerceValue(fields[j], item)
+			if err != nil {
+				f.addError("_tuple_in", fields[j], err.Error())
+				return db
+			}
+			coerced[j] = c
+		}
+		tuples[i] = coerced
+	}
+	if len(tuples) == 0 {
+		f.recordSQL("TUPLE_IN(EMPTY)", "1 = 0")
+		return db.Where("1 = 0")
+	}
+
+	rowValues := dialectName(db) == "mysql" || dialectName(db) == "postgres"
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(fields)), ", ") + ")"
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = quoteIdent(db, field)
+	}
+	group := newSession(db)
+	for start := 0; start < len(tuples); start += tupleInChunkSize {
+		end := start + tupleInChunkSize
+		if end > len(tuples) {
+			end = len(tuples)
+		}
+		var (
+			parts []string
+			args  []interface{}
+		)
+		for _, tuple := range tuples[start:end] {
+			if rowValues {
+				parts = append(parts, placeholder)
+			} else {
+				conds := make([]string, len(columns))
+				for i, col := range columns {
+					conds[i] = col + " = ?"
+				}
+				parts = append(parts, "("+strings.Join(conds, " AND ")+")")
+			}
+			args = append(args, tuple...)
+		}
+		if rowValues {
+			group = group.Or(fmt.Sprintf("(%s) IN (%s)", strings.Join(columns, ", "), strings.Join(parts, ", ")), args...)
+		} else {
+			group = group.Or(strings.Join(parts, " OR "), args...)
+		}
+	}
+	f.recordSQL(fmt.Sprintf("TUPLE_IN %s", strings.Join(fields, ",")), len(tuples))
+	return db.Where(group)
+}
+
+// 应用全局搜索，多列之间 OR 并整体与其他条件 AND
+func (f *Filter) applySearch(db *gorm.DB) *gorm.DB {
+	fields := make([]string, 0, len(f.SearchFields))
+	for _, field := range f.SearchFields {
+		field = f.resolveAlias(field)
+		if !identifierPattern.MatchString(field) || !f.isFilterable(field) {
+			f.reject(field, "search", "field is not filterable")
+			continue
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) == 0 {
+		return db
+	}
+	if dialect := dialectName(db); f.SearchMode == "match" && (dialect == "mysql" || dialect == "postgres") {
+		joined := strings.Join(fields, ",")
+		column, _ := f.columnExpr(db, joined)
+		return f.applyComplexCondition(db, joined, column, map[string]interface{}{"match": f.Search})
+	}
+	pattern := "%" + escapeLike(f.Search) + "%"
+	group := newSession(db)
+	for _, field := range fields {
+		group = group.Or(fmt.Sprintf("%s LIKE ? ESCAPE %s", quoteIdent(db, field), likeEscapeLiteral(db)), pattern)
+	}
+	f.recordSQL(fmt.Sprintf("SEARCH %s", strings.Join(fields, ",")), pattern)
+	return db.Where(group)
+}
+
+// 应用 IN 条件，空数组生成恒假条件 1 = 0，避免非法的 IN ()
+func (f *Filter) applyIn(db *gorm.DB, field, column string, value interface{}) *gorm.DB {
+	if n, ok := sliceLen(value); ok && n == 0 {
+		f.recordSQL(fmt.Sprintf("IN(EMPTY) %s", field), "1 = 0")
+		return db.Where("1 = 0")
+	}
+	f.recordSQL(fmt.Sprintf("IN %s", field), value)
+	return db.Where(fmt.Sprintf("%s IN (?)", column), value)
+}
+
+// 应用复杂条件（如 like、gt、between），field 为原始字段名，column 为 SQL 中使用的列表达式。
+// 数组值的提升规则：eq 提升为 in，neq 提升为 not_in；
+// 空数组时 eq/in 生成恒假条件 1 = 0，neq/not_in 跳过该条件
+func (f *Filter) applyComplexCondition(db *gorm.DB, field, column string, conds map[string]interface{}) *gorm.DB {
+	for op, value := range conds {
+		// Mongo 风格的 $ 运算符映射为内置运算符
+		if strings.HasPrefix(op, "$") {
+			var ok bool
+			if op, value, ok = mongoOperator(op, value); !ok {
+				f.addError(field, op, "unknown operator")
+				continue
+			}
+		}
+		if f.isIgnored(value) {
+			if f.rejectIgnored {
+				f.addError(field, op, "empty value is not allowed")
+				continue
+			}
+			f.recordSQL(fmt.Sprintf("SKIP ZERO %s %s", strings.ToUpper(op), field), value)
+			continue
+		}
+		if comparisonOps[op] {
+			var err error
+			if value, err = f.coerceValue(field, value); err != nil {
+				f.addError(field, op, err.Error())
+				continue
+			}
+		}
+		if _, isBytes := value.([]byte); !isBytes && isSliceValue(value) {
+			switch op {
+			case "eq":
+				op = "in"
+			case "neq":
+				op = "not_in"
+			}
+		}
+		// eq/neq 的值为 null 时改写为 IS NULL / IS NOT NULL，避免永不成立的 = NULL
+		if value == nil && (op == "eq" || op == "neq") {
+			if op == "eq" {
+				db = db.Where(fmt.Sprintf("%s IS NULL", column))
+				f.recordSQL(fmt.Sprintf("IS_NULL %s", field), nil)
+			} else {
+				db = db.Where(fmt.Sprintf("%s IS NOT NULL", column))
+				f.recordSQL(fmt.Sprintf("NOT_NULL %s", field), nil)
+			}
+			continue
+		}
+		switch op {
+		case "eq":
+			db = db.Where(fmt.Sprintf("%s = ?", column), value)
+			f.recordSQL(fmt.Sprintf("EQ %s", field), value)
+		case "nseq":
+			// NULL 安全的等于，两侧都为 NULL 时成立
+			var tpl string
+			switch dialectName(db) {
+			case "mysql":
+				tpl = "%s <=> ?"
+			case "postgres":
+				tpl = "%s IS NOT DISTINCT FROM ?"
+			default:
+				tpl = "%s IS ?"
+			}
+			db = db.Where(fmt.Sprintf(tpl, column), value)
+			f.recordSQL(fmt.Sprintf("NSEQ %s", field), value)
+		case "eq_ci":
+			db = db.Where(fmt.Sprintf("LOWER(%s) = LOWER(?)", column), value)
+			f.recordSQL(fmt.Sprintf("EQ_CI %s", field), value)
+		case "neq":
+			db = db.Where(fmt.Sprintf("%s != ?", column), value)
+			f.recordSQL(fmt.Sprintf("NEQ %s", field), value)
+		case "gt":
+			db = db.Where(fmt.Sprintf("%s > ?", column), value)
+			f.recordSQL(fmt.Sprintf("GT %s", field), value)
+		case "gte":
+			db = db.Where(fmt.Sprintf("%s >= ?", column), value)
+			f.recordSQL(fmt.Sprintf("GTE %s", field), value)
+		case "lt":
+			db = db.Where(fmt.Sprintf("%s < ?", column), value)
+			f.recordSQL(fmt.Sprintf("LT %s", field), value)
+		case "lte":
+			db = db.Where(fmt.Sprintf("%s <= ?", column), value)
+			f.recordSQL(fmt.Sprintf("LTE %s", field), value)
+		// like 系列的模式中反斜杠为转义符，各数据库一致
+		case "like":
+			db = db.Where(fmt.Sprintf("%s LIKE ? ESCAPE %s", column, likeEscapeLiteral(db)), fmt.Sprintf("%v", value))
+			f.recordSQL(fmt.Sprintf("LIKE %s", field), value)
+		case "not_like":
+			db = db.Where(fmt.Sprintf("%s NOT LIKE ? ESCAPE %s", column, likeEscapeLiteral(db)), fmt.Sprintf("%v", value))
+			f.recordSQL(fmt.Sprintf("NOT_LIKE %s", field), value)
+		case "ilike":
+			// Postgres 原生支持 ILIKE，其余方言两侧转小写后比较
+			if dialectName(db) == "postgres" {
+				db = db.Where(fmt.Sprintf("%s ILIKE ? ESCAPE %s", column, likeEscapeLiteral(db)), fmt.Sprintf("%v", value))
+				f.recordSQL(fmt.Sprintf("ILIKE %s", field), value)
+			} else {
+				db = db.Where(fmt.Sprintf("LOWER(%s) LIKE LOWER(?) ESCAPE %s", column, likeEscapeLiteral(db)), fmt.Sprintf("%v", value))
+				f.recordSQL(fmt.Sprintf("ILIKE(LOWER LIKE) %s", field), value)
+			}
+		case "regexp", "not_regexp":
+			pattern := fmt.Sprintf("%v", value)
+			if pattern == "" {
+				continue
+			}
+			// 非法正则交由数据库报错
+			var tpl string
+			if dialectName(db) == "postgres" {
+				tpl = "%s ~ ?"
+				if op == "not_regexp" {
+					tpl = "%s !~ ?"
+				}
+			} else {
+				tpl = "%s REGEXP ?"
+				if op == "not_regexp" {
+					tpl = "%s NOT REGEXP ?"
+				}
+			}
+			db = db.Where(fmt.Sprintf(tpl, column), pattern)
+			f.recordSQL(fmt.Sprintf("%s %s", strings.ToUpper(op), field), pattern)
+		case "json_contains":
+			// 值按 JSON 编码后绑定
+			raw, err := json.Marshal(value)
+			if err != nil {
+				f.addError(field, op, err.Error())
+				continue
+			}
+			if dialectName(db) == "postgres" {
+				db = db.Where(fmt.Sprintf("%s @> ?::jsonb", column), string(raw))
+			} else {
+				db = db.Where(fmt.Sprintf("JSON_CONTAINS(%s, ?)", column), string(raw))
+			}
+			f.recordSQL(fmt.Sprintf("JSON_CONTAINS %s", field), string(raw))
+		case "overlaps", "array_contains":
+			// 仅 Postgres 原生数组列支持
+			if d := dialectName(db); d != "" && d != "postgres" {
+				f.addError(field, op, "only supported on postgres")
+				continue
+			}
+			literal, err := pgArrayLiteral(value)
+			if err != nil {
+				f.addError(field, op, err.Error())
+				continue
+			}
+			sqlOp := "&&"
+			if op == "array_contains" {
+				sqlOp = "@>"
+			}
+			db = db.Where(fmt.Sprintf("%s %s ?", column, sqlOp), literal)
+			f.recordSQL(fmt.Sprintf("%s %s", strings.ToUpper(op), field), literal)
+		case "find_in_set":
+			item := fmt.Sprintf("%v", value)
+			if dialectName(db) == "mysql" {
+				db = db.Where(fmt.Sprintf("FIND_IN_SET(?, %s) > 0", column), item)
+				f.recordSQL(fmt.Sprintf("FIND_IN_SET %s", field), item)
+			} else {
+				// 其他方言首尾补逗号后用 LIKE 匹配 ",值,"
+				pattern := "%," + escapeLike(item) + ",%"
+				db = db.Where(fmt.Sprintf("(',' || %s || ',') LIKE ? ESCAPE %s", column, likeEscapeLiteral(db)), pattern)
+				f.recordSQL(fmt.Sprintf("FIND_IN_SET(LIKE) %s", field), pattern)
+			}
+		case "match":
+			keyword := fmt.Sprintf("%v", value)
+			if keyword == "" {
+				continue
+			}
+			switch dialectName(db) {
+			case "mysql", "":
+				db = db.Where(fmt.Sprintf("MATCH(%s) AGAINST (? IN NATURAL LANGUAGE MODE)", column), keyword)
+			case "postgres":
+				doc := column
+				if strings.Contains(column, ",") {
+					doc = fmt.Sprintf("concat_ws(' ', %s)", column)
+				}
+				db = db.Where(fmt.Sprintf("to_tsvector(%s) @@ plainto_tsquery(?)", doc), keyword)
+			default:
+				f.addError(field, op, fmt.Sprintf("full-text search is not supported on %s", dialectName(db)))
+				continue
+			}
+			f.recordSQL(fmt.Sprintf("MATCH %s", field), keyword)
+		case "date_eq", "date_gte", "date_lte":
+			// 按天展开为左闭右开区间
+			day, err := f.parseDay(value)
+			if err != nil {
+				f.addError(field, op, err.Error())
+				continue
+			}
+			next := day.AddDate(0, 0, 1)
+			day, next = f.bindTime(day), f.bindTime(next)
+			switch op {
+			case "date_eq":
+				db = db.Where(fmt.Sprintf("%s >= ? AND %s < ?", column, column), day, next)
+				f.recordSQL(fmt.Sprintf("DATE_EQ %s", field), []time.Time{day, next})
+			case "date_gte":
+				db = db.Where(fmt.Sprintf("%s >= ?", column), day)
+				f.recordSQL(fmt.Sprintf("DATE_GTE %s", field), day)
+			default:
+				db = db.Where(fmt.Sprintf("%s < ?", column), next)
+				f.recordSQL(fmt.Sprintf("DATE_LTE %s", field), next)
+			}
+		case "range":
+			start, end, err := f.relativeRange(value)
+			if err != nil {
+				f.addError(field, op, err.Error())
+				continue
+			}
+			db = db.Where(fmt.Sprintf("%s BETWEEN ? AND ?", column), start, end)
+			f.recordSQL(fmt.Sprintf("RANGE %s", field), []time.Time{start, end})
+		case "len_eq", "len_gt", "len_lt":
+			n, err := toInt64(value)
+			if err != nil {
+				f.addError(field, op, err.Error())
+				continue
+			}
+			fn := "CHAR_LENGTH"
+			if dialectName(db) == "sqlite" {
+				fn = "LENGTH"
+			}
+			sqlOp := map[string]string{"len_eq": "=", "len_gt": ">", "len_lt": "<"}[op]
+			db = db.Where(fmt.Sprintf("%s(%s) %s ?", fn, column, sqlOp), n)
+			f.recordSQL(fmt.Sprintf("%s %s", strings.ToUpper(op), field), n)
+		case "has_flag", "not_has_flag":
+			mask, err := toInt64(value)
+			if err != nil {
+				f.addError(field, op, err.Error())
+				continue
+			}
+			if op == "has_flag" {
+				db = db.Where(fmt.Sprintf("(%s & ?) = ?", column), mask, mask)
+			} else {
+				db = db.Where(fmt.Sprintf("(%s & ?) = 0", column), mask)
+			}
+			f.recordSQL(fmt.Sprintf("%s %s", strings.ToUpper(op), field), mask)
+		case "starts_with", "ends_with", "contains":
+			// 用户输入中的 % _ \ 按字面量匹配
+			pattern := escapeLike(fmt.Sprintf("%v", value))
+			switch op {
+			case "starts_with":
+				pattern = pattern + "%"
+			case "ends_with":
+				pattern = "%" + pattern
+			default:
+				pattern = "%" + pattern + "%"
+			}
+			db = db.Where(fmt.Sprintf("%s LIKE ? ESCAPE %s", column, likeEscapeLiteral(db)), pattern)
+			f.recordSQL(fmt.Sprintf("%s %s", strings.ToUpper(op), field), pattern)
+		case "in":
+			db = f.applyIn(db, field, column, value)
+		case "not_in":
+			// 空切片会生成非法的 NOT IN ()，直接跳过
+			if n, ok := sliceLen(value); ok && n == 0 {
+				continue
+			}
+			db = db.Where(fmt.Sprintf("%s NOT IN (?)", column), value)
+			f.recordSQL(fmt.Sprintf("NOT_IN %s", field), value)
+		case "is_null":
+			// 值为 false 时跳过该条件，而不是取反
+			if truthy(value) {
+				db = db.Where(fmt.Sprintf("%s IS NULL", column))
+				f.recordSQL(fmt.Sprintf("IS_NULL %s", field), nil)
+			}
+		case "not_null":
+			// 值为 false 时跳过该条件，而不是取反
+			if truthy(value) {
+				db = db.Where(fmt.Sprintf("%s IS NOT NULL", column))
+				f.recordSQL(fmt.Sprintf("NOT_NULL %s", field), nil)
+			}
+		case "between":
+			arr, ok := value.([]interface{})
+			if !ok || len(arr) != 2 {
+				f.addError(field, op, "requires an array of 2 elements")
+				continue
+			}
+			db = db.Where(fmt.Sprintf("%s BETWEEN ? AND ?", column), arr[0], arr[1])
+			f.recordSQL(fmt.Sprintf("BETWEEN %s", field), arr)
+		case "not_between":
+			// 静默忽略会让排除条件失效，长度不对直接报错
+			arr, ok := value.([]interface{})
+			if !ok || len(arr) != 2 {
+				f.addError(field, op, "requires an array of 2 elements")
+				continue
+			}
+			db = db.Where(fmt.Sprintf("%s NOT BETWEEN ? AND ?", column), arr[0], arr[1])
+			f.recordSQL(fmt.Sprintf("NOT_BETWEEN %s", field), arr)
+		default:
+			f.reject(field, op, "unknown operator")
+		}
+	}
+	return db
+}
+
+// ApplySortAndPagination 排序分页，错误挂在返回的 *gorm.DB 上，需要直接获取错误时使用 ApplySortAndPaginationE
+func (f *Filter) ApplySortAndPagination(db *gorm.DB) *gorm.DB {
+	db, _ = f.ApplySortAndPaginationE(db)
+	return db
+}
+
+// ApplySortAndPaginationE 同 ApplySortAndPagination，同时返回排序字段校验等错误。
+// 排序字段支持 JOIN 表的列（如 "-roles.name"），Sortable 可用 "roles.*" 放行整张表；
+// 同时开启 Distinct 时，Postgres 要求 ORDER BY 的列出现在查询列中，需通过 Fields 一并查询
+func (f *Filter) ApplySortAndPaginationE(db *gorm.DB) (*gorm.DB, error) {
+	errStart := len(f.errs)
+
+	// 按 id 游标分页时替代排序和 OFFSET
+	keyset := f.AfterID > 0 || f.BeforeID > 0
+	if keyset {
+		db = f.applyKeyset(db)
+	}
+
+	// 排序
+	var sorted []string
+	if f.RandomOrder && !keyset {
+		db = f.applyRandomOrder(db)
+	}
+	if f.Sort != "" && !keyset {
+		seen := make(map[string]bool)
+		for _, s := range strings.Split(f.Sort, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			term, err := parseSortTerm(s)
+			if err != nil {
+				f.reject(s, "sort", err.Error())
+				continue
+			}
+			field := term.field
+			// 重复的排序字段只保留第一次出现
+			if seen[field] {
+				continue
+			}
+			seen[field] = true
+			// 随机排序需在 Sortable 中显式放行 "random"，通配符不生效
+			if field == "random" {
+				switch {
+				case f.RandomOrder:
+					// 已随机排序
+				case containsString(f.Sortable, "random"):
+					db = f.applyRandomOrder(db)
+				default:
+					f.reject(field, "sort", f.notSortableReason())
+				}
+				continue
+			}
+			// 已注册的排序表达式直接使用，表达式来自 Go 代码
+			if expr, ok := f.SortExprs[field]; ok {
+				db = db.Order(f.orderExpr(db, expr, term))
+				f.recordSQL(strings.TrimSpace(fmt.Sprintf("ORDER EXPR %s %s", field, term.modifiers())), nil)
+				continue
+			}
+			// 排序别名按对外名称校验，映射的列来自 Go 代码
+			if col, ok := f.SortAliases[field]; ok {
+				if !f.isSortable(field) {
+					f.reject(field, "sort", f.notSortableReason())
+					continue
+				}
+				db = db.Order(f.orderExpr(db, quoteIdent(db, col), term))
+				f.recordSQL(strings.TrimSpace(fmt.Sprintf("ORDER %s -> %s %s", field, col, term.modifiers())), nil)
+				sorted = append(sorted, col)
+				continue
+			}
+			// 排序只接受字段名或 "表名.字段名"，不支持 JSON 路径
+			if _, ok := f.FieldAliases[field]; !ok && !identifierPattern.MatchString(field) {
+				f.addError(field, "sort", "invalid field name")
+				continue
+			}
+			field = f.resolveAlias(field)
+			if !f.isSortable(field) {
+				f.reject(term.field, "sort", f.notSortableReason())
+				continue
+			}
+			db = db.Order(f.orderExpr(db, quoteIdent(db, f.sortColumn(field)), term))
+			f.recordSQL(strings.TrimSpace(fmt.Sprintf("ORDER %s %s", field, term.modifiers())), nil)
+			sorted = append(sorted, field)
+		}
+	}
+	// 追加唯一列作为最后的排序依据，避免排序值相同的记录在翻页时重复或遗漏
+	if f.StableSort && !keyset {
+		if col := f.tiebreakerColumn(); col != "" && !f.containsColumn(sorted, col) {
+			db = db.Order(fmt.Sprintf("%s ASC", quoteIdent(db, col)))
+			f.recordSQL(fmt.Sprintf("ORDER TIEBREAKER %s ASC", col), nil)
+		}
+	}
+
+	// 分页
+	var pageErr error
+	switch {
+	case f.NoPagination:
+		// 不分页，只保留排序
+		f.recordSQL("NO PAGINATION", nil)
+	case keyset:
+		f.checkPageSize()
+		db = db.Limit(f.PageSize)
+		f.recordSQL("Pagination", map[string]int{"pageSize": f.PageSize})
+	default:
+		f.checkPageSize()
+		if err := f.checkOffset(); err != nil {
+			pageErr = err
+			break
+		}
+		offset := (f.Page - 1) * f.PageSize
+		db = db.Offset(offset).Limit(f.PageSize)
+		f.recordSQL("Pagination", map[string]int{"page": f.Page, "pageSize": f.PageSize})
+	}
+	if f.Debug {
+		sql := db.Session(&gorm.Session{DryRun: true}).ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return tx.Find(nil)
+		})
+		f.finalSQL = sql
+	}
+	// 本次新增的错误挂到 db 上
+	var err error
+	if len(f.errs) > errStart {
+		err = joinFilterErrors(f.errs[errStart:])
+	}
+	if pageErr != nil {
+		err = errors.Join(err, pageErr)
+	}
+	if err != nil {
+		_ = db.AddError(err)
+	}
+	return db, err
+}
+
+// 校验并补全分页参数，严格模式下每页条数超过上限时报错
+func (f *Filter) checkPageSize() {
+	if max := f.maxPageSize(); f.Strict && f.PageSize > max {
+		f.addError("page_size", "", fmt.Sprintf("must not exceed %d", max))
+	}
+	f.normalizePage()
+}
+
+// 全局默认的最大偏移量，0 表示不限制
+var defaultMaxOffset atomic.Int64
+
+// SetDefaultMaxOffset 设置全局的最大偏移量 (page-1)*pageSize，超出时返回 ErrPageOutOfRange；0 表示不限制（默认）
+func SetDefaultMaxOffset(offset int) {
+	if offset >= 0 {
+		defaultMaxOffset.Store(int64(offset))
+	}
+}
+
+func (f *Filter) maxOffset() int {
+	if f.MaxOffset != 0 {
+		return f.MaxOffset
+	}
+	return int(defaultMaxOffset.Load())
+}
+
+// 偏移量超过 MaxOffset 时返回 *PageOutOfRangeError
+func (f *Filter) checkOffset() error {
+	f.normalizePage()
+	max := f.maxOffset()
+	if max <= 0 || (f.Page-1)*f.PageSize <= max {
+		return nil
+	}
+	return &PageOutOfRangeError{Page: f.Page, MaxPage: max/f.PageSize + 1}
+}
+
+// StableSort 使用的唯一列，默认为模型表的主键；模型没有主键时返回空，不追加排序
+func (f *Filter) tiebreakerColumn() string {
+	if f.TiebreakerColumn != "" {
+		return f.TiebreakerColumn
+	}
+	if f.schema == nil {
+		return "id"
+	}
+	pk := f.schema.PrioritizedPrimaryField
+	if pk == nil {
+		return ""
+	}
+	return f.schema.Table + "." + pk.DBName
+}
+
+// 排序列在 SQL 中的名称：有 JOIN 时 id 指模型表的 id，加上表名避免与 JOIN 表的 id 冲突
+func (f *Filter) sortColumn(field string) string {
+	if field == "id" && len(f.Joins) > 0 {
+		return f.keysetColumn()
+	}
+	return field
+}
+
+// 列是否已在列表中，模型表的列带不带表名视为同一列
+func (f *Filter) containsColumn(list []string, col string) bool {
+	bare := func(c string) string {
+		if f.schema != nil {
+			if name, ok := strings.CutPrefix(c, f.schema.Table+"."); ok {
+				return name
+			}
+		}
+		return c
+	}
+	for _, c := range list {
+		if bare(c) == bare(col) {
+			return true
+		}
+	}
+	return false
+}
+
+// 分页参数默认值：页码 1，每页 10 条，超过最大条数时取最大条数
+func (f *Filter) normalizePage() {
+	if f.Page <= 0 {
+		f.Page = 1
+	}
+	if f.PageSize <= 0 {
+		f.PageSize = 10
+	}
+	if max := f.maxPageSize(); f.PageSize > max {
+		f.PageSize = max
+	}
+}
+
+// 默认每页最大条数
+var defaultMaxPageSize atomic.Int64
+
+func init() {
+	defaultMaxPageSize.Store(500)
+}
+
+// SetDefaultMaxPageSize 设置全局的每页最大条数（默认 500），size <= 0 时忽略。单个查询请使用 Filter.MaxPageSize
+func SetDefaultMaxPageSize(size int) {
+	if size > 0 {
+		defaultMaxPageSize.Store(int64(size))
+	}
+}
+
+func (f *Filter) maxPageSize() int {
+	if f.MaxPageSize > 0 {
+		return f.MaxPageSize
+	}
+	return int(defaultMaxPageSize.Load())
+}
+
+// 清空调试记录、错误等内部状态
+func (f *Filter) resetState() {
+	f.sqlRecords = nil
+	f.finalSQL = ""
+	f.errs = nil
+	f.subqueryConds = nil
+	f.schema = nil
+	f.prevCursor = ""
+}
+
+// 记录调试 SQL
+func (f *Filter) recordSQL(desc string, val interface{}) {
+	if !f.Debug {
+		return
+	}
+	if f.applyingBase {
+		desc = "BASE " + desc
+	}
+	f.sqlRecords = append(f.sqlRecords, fmt.Sprintf("[%s] | args: %v", desc, val))
+}
+
+// 记录筛选条件错误
+func (f *Filter) addError(field, op, reason string) {
+	f.errs = append(f.errs, FilterError{Field: field, Op: op, Reason: reason})
+	f.recordSQL(strings.TrimSpace(fmt.Sprintf("ERROR %s %s", field, op)), reason)
+}
+
+// 拒绝字段或运算符：非严格模式只记录调试信息，严格模式记为错误
+func (f *Filter) reject(field, op, reason string) {
+	if f.Strict {
+		f.addError(field, op, reason)
+		return
+	}
+	f.recordSQL(strings.TrimSpace(fmt.Sprintf("SKIP %s %s", field, op)), reason)
+}
+
+// 合并后的筛选条件错误
+func (f *Filter) err() error {
+	return joinFilterErrors(f.errs)
+}
+
+// PrintSQLs 打印调试信息
+func (f *Filter) PrintSQLs() {
+	fmt.Println("=== Generated SQL Statements ===")
+	for i, s := range f.sqlRecords {
+		fmt.Printf("%d. %s\n", i+1, s)
+	}
+	if f.finalSQL != "" {
+		fmt.Println("---------------------------------")
+		fmt.Printf("[Final SQL Preview]\n%s\n", f.finalSQL)
+	}
+	fmt.Println("=================================")
+}
+
+// JSON 路径片段只允许字母、数字和下划线，防止通过路径注入 SQL
+var jsonPathSegment = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// JSON 路径写法 "列名.$.路径" 所属的列
+func jsonColumn(field string) string {
+	col, _, _ := strings.Cut(field, ".$.")
+	return col
+}
+
+// 解析字段在 SQL 中的列表达式，支持 JSON 路径写法 "metadata.$.region"
+func (f *Filter) columnExpr(db *gorm.DB, field string) (string, error) {
+	col, path, ok := strings.Cut(field, ".$.")
+	if !ok {
+		// 逗号分隔的多列（match）逐列引用
+		if strings.Contains(field, ",") {
+			cols := strings.Split(field, ",")
+			for i, c := range cols {
+				cols[i] = quoteIdent(db, strings.TrimSpace(c))
+			}
+			return strings.Join(cols, ", "), nil
+		}
+		return quoteIdent(db, field), nil
+	}
+	col = quoteIdent(db, col)
+	segs := strings.Split(path, ".")
+	for _, seg := range segs {
+		if !jsonPathSegment.MatchString(seg) {
+			return "", fmt.Errorf("invalid json path segment %q", seg)
+		}
+	}
+	switch dialectName(db) {
+	case "postgres":
+		expr := col
+		for i, seg := range segs {
+			if i == len(segs)-1 {
+				expr += "->>'" + seg + "'"
+			} else {
+				expr += "->'" + seg + "'"
+			}
+		}
+		return expr, nil
+	case "mysql":
+		return fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '$.%s'))", col, path), nil
+	default:
+		return fmt.Sprintf("JSON_EXTRACT(%s, '$.%s')", col, path), nil
+	}
+}
+
+// 将切片转换为 Postgres 数组字面量，如 {"a","b"}
+func pgArrayLiteral(value interface{}) (string, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return "", errors.New("requires an array value")
+	}
+	items := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i).Interface()
+		switch v := item.(type) {
+		case nil:
+			items = append(items, "NULL")
+		case string:
+			items = append(items, `"`+strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v)+`"`)
+		default:
+			items = append(items, fmt.Sprintf("%v", v))
+		}
+	}
+	return "{" + strings.Join(items, ",") + "}", nil
+}
+
+// 按 IgnoreZero/IgnoreZeroNumber 判断是否跳过该值
+func (f *Filter) isIgnored(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return f.IgnoreZero
+	case string:
+		return f.IgnoreZero && v == ""
+	case int:
+		return f.IgnoreZeroNumber && v == 0
+	case int64:
+		return f.IgnoreZeroNumber && v == 0
+	case float64:
+		return f.IgnoreZeroNumber && v == 0
+	}
+	return false
+}
+
+// 需要转换值类型的比较运算符
+var comparisonOps = map[string]bool{
+	"eq": true, "neq": true, "gt": true, "gte": true, "lt": true, "lte": true,
+	"in": true, "not_in": true, "between": true, "not_between": true,
+}
+
+// Mongo 风格运算符到内置运算符的映射
+var mongoOperators = map[string]string{
+	"$eq": "eq", "$ne": "neq", "$gt": "gt", "$gte": "gte", "$lt": "lt", "$lte": "lte",
+	"$in": "in", "$nin": "not_in", "$regex": "regexp",
+}
+
+// 转换 Mongo 风格运算符，$exists 按值转为 not_null / is_null；未知运算符返回原运算符和 false
+func mongoOperator(op string, value interface{}) (string, interface{}, bool) {
+	if op == "$exists" {
+		if truthy(value) {
+			return "not_null", true, true
+		}
+		return "is_null", true, true
+	}
+	if mapped, ok := mongoOperators[op]; ok {
+		return mapped, value, true
+	}
+	return op, value, false
+}
+
+// 时间字段可接受的字符串格式
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// 按字段类型转换条件值，数组逐个转换。类型优先取 FieldTypes，其次取模型结构体中的字段类型
+func (f *Filter) coerceValue(field string, value interface{}) (interface{}, error) {
+	kind := f.fieldKind(field)
+	if kind == "" {
+		return value, nil
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			c, err := coerceScalar(kind, item, f.Location)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = c
+		}
+		return res, nil
+	case []string:
+		res := make([]interface{}, len(v))
+		for i, item := range v {
+			c, err := coerceScalar(kind, item, f.Location)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = c
+		}
+		return res, nil
+	}
+	return coerceScalar(kind, value, f.Location)
+}
+
+// 字段的值类型：time、int、float、bool，未知返回空
+func (f *Filter) fieldKind(field string) string {
+	if kind, ok := f.FieldTypes[field]; ok {
+		return kind
+	}
+	if f.schema == nil {
+		return ""
+	}
+	// 带表名前缀时只处理主表的列
+	name := field
+	if table, col, ok := strings.Cut(field, "."); ok {
+		if table != f.schema.Table {
+			return ""
+		}
+		name = col
+	}
+	sf := f.schema.LookUpField(name)
+	if sf == nil {
+		return ""
+	}
+	t := sf.FieldType
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "time"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Bool:
+		return "bool"
+	}
+	return ""
+}
+
+// 转换单个值，JSON 数字为 float64，字符串按目标类型解析，loc 为不带时区的时间字符串所在的时区
+func coerceScalar(kind string, value interface{}, loc *time.Location) (interface{}, error) {
+	switch kind {
+	case "time":
+		if s, ok := value.(string); ok {
+			return parseTime(s, loc)
+		}
+	case "int":
+		switch value.(type) {
+		case float64, string:
+			return toInt64(value)
+		}
+	case "float":
+		if s, ok := value.(string); ok {
+			n, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", s)
+			}
+			return n, nil
+		}
+	case "bool":
+		switch v := value.(type) {
+		case string:
+			switch v {
+			case "true", "1":
+				return true, nil
+			case "false", "0":
+				return false, nil
+			}
+			return nil, fmt.Errorf("%q is not a boolean", v)
+		case float64:
+			if v == 0 || v == 1 {
+				return v == 1, nil
+			}
+			return nil, fmt.Errorf("%v is not a boolean", v)
+		}
+	}
+	return value, nil
+}
+
+// 按 timeLayouts 依次尝试解析时间
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if loc == nil {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		} else if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as time", s)
+}
+
+// 日期条件使用的时区
+func (f *Filter) location() *time.Location {
+	if f.Location != nil {
+		return f.Location
+	}
+	return time.Local
+}
+
+// 展开相对日期快捷值，返回闭区间 [start, end]
+func (f *Filter) relativeRange(value interface{}) (time.Time, time.Time, error) {
+	now := time.Now
+	if f.Now != nil {
+		now = f.Now
+	}
+	t := now().In(f.location())
+	today := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	tomorrow := today.AddDate(0, 0, 1)
+
+	var start, end time.Time
+	switch value {
+	case "today":
+		start, end = today, tomorrow
+	case "yesterday":
+		start, end = today.AddDate(0, 0, -1), today
+	case "last_7_days":
+		start, end = today.AddDate(0, 0, -6), tomorrow
+	case "last_30_days":
+		start, end = today.AddDate(0, 0, -29), tomorrow
+	case "this_month":
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		end = start.AddDate(0, 1, 0)
+	case "this_year":
+		start = time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+		end = start.AddDate(1, 0, 0)
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown range %v", value)
+	}
+	// BETWEEN 为闭区间，结束时间取下一周期开始前 1 微秒
+	return f.bindTime(start), f.bindTime(end.Add(-time.Microsecond)), nil
+}
+
+// 设置了 Location 时，按该时区计算出的时间转换为 UTC 再绑定；未设置时保持原样
+func (f *Filter) bindTime(t time.Time) time.Time {
+	if f.Location != nil {
+		return t.UTC()
+	}
+	return t
+}
+
+// 解析 YYYY-MM-DD 格式的日期，返回当天零点
+func (f *Filter) parseDay(value interface{}) (time.Time, error) {
+	str, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid date %v, expected YYYY-MM-DD", value)
+	}
+	day, err := time.ParseInLocation("2006-01-02", str, f.location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", str)
+	}
+	return day, nil
+}
+
+// 创建不带任何条件的新会话，用于构建条件组
+func newSession(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true})
+}
+
+// 是否已经生成 WHERE 条件
+func hasWhere(db *gorm.DB) bool {
+	_, ok := db.Statement.Clauses["WHERE"]
+	return ok
+}
+
+// 将条件组的值转换为条件 map 列表，兼容 JSON 解析结果和 Go 代码中的写法
+func conditionMaps(value interface{}) ([]map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case []map[string]interface{}:
+		return v, true
+	case []interface{}:
+		res := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			res = append(res, m)
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+// 将条件值转换为 int64，JSON 数字为 float64，带小数时报错
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case uint:
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+		return int64(v), nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not an integer", v)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("%v is not an integer", value)
+}
+
+func isSliceValue(v interface{}) bool {
+	_, ok := sliceLen(v)
+	return ok
+}
+
+// 获取切片长度，非切片返回 false
+func sliceLen(v interface{}) (int, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return 0, false
+	}
+	return rv.Len(), true
+}
+
+// 当前数据库方言名称，如 mysql、postgres、sqlite
+func dialectName(db *gorm.DB) string {
+	if db == nil || db.Dialector == nil {
+		return ""
+	}
+	return db.Dialector.Name()
+}
+
+// 转义 LIKE 模式中的通配符，转义符为反斜杠
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// ESCAPE 子句中的反斜杠字面量，MySQL 字符串中反斜杠本身需要转义
+func likeEscapeLiteral(db *gorm.DB) string {
+	if dialectName(db) == "mysql" {
+		return `'\\'`
+	}
+	return `'\'`
+}
+
+// 判断布尔型条件值，兼容 JSON 中的 true/1/"true"
+func truthy(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true" || b == "1"
+	case int:
+		return b != 0
+	case float64:
+		return b != 0
+	}
+	return false
+}
+
+// 按方言引用列名或表名，"表名.字段名" 两部分分别引用；不是合法标识符（如带别名的表）时原样返回
+func quoteIdent(db *gorm.DB, name string) string {
+	if db.Statement == nil || !identifierPattern.MatchString(name) {
+		return name
+	}
+	return db.Statement.Quote(name)
+}
+
+// 合法的列名：字段名或 "表名.字段名"
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// 字段名是否为合法标识符：已配置的别名直接放行，JSON 路径按所属列判断，逗号分隔的多列逐个判断
+func (f *Filter) safeField(field string) bool {
+	if _, ok := f.FieldAliases[field]; ok {
+		return true
+	}
+	for _, col := range strings.Split(jsonColumn(field), ",") {
+		if !identifierPattern.MatchString(strings.TrimSpace(col)) {
+			return false
+		}
+	}
+	return true
+}
+
+// 过滤出可查询的列，不合法的列跳过
+func (f *Filter) selectableFields(input []string, desc string) []string {
+	allowed := f.Selectable
+	if len(allowed) == 0 {
+		allowed = append(append([]string{}, f.Filterable...), f.Sortable...)
+	}
+	fields := make([]string, 0, len(input))
+	for _, field := range input {
+		if !identifierPattern.MatchString(field) || (len(allowed) > 0 && !containsString(allowed, field)) {
+			f.reject(field, strings.ToLower(desc), "field is not selectable")
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 按 FieldAliases 转换字段名，未配置的原样返回
+func (f *Filter) resolveAlias(field string) string {
+	if col, ok := f.FieldAliases[field]; ok {
+		return col
+	}
+	return field
+}
+
+// 排序字段转换为列，SortAliases 优先于 FieldAliases
+func (f *Filter) resolveSortAlias(field string) string {
+	if col, ok := f.SortAliases[field]; ok {
+		return col
+	}
+	return f.resolveAlias(field)
+}
+
+// 逗号分隔的多列需全部可筛选
+func (f *Filter) isFilterableColumns(field string) bool {
+	for _, col := range strings.Split(field, ",") {
+		if !f.isFilterable(col) {
+			return false
+		}
+	}
+	return true
+}
+
+// 条件是否只包含 match 运算符
+func onlyMatch(value interface{}) bool {
+	conds, ok := value.(map[string]interface{})
+	if !ok || len(conds) == 0 {
+		return false
+	}
+	for op := range conds {
+		if op != "match" {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Filter) isFilterable(field string) bool {
+	if len(f.Filterable) == 0 {
+		return true
+	}
+	return matchWhitelist(f.Filterable, field)
+}
+
+// 通配符匹配的字段名只允许字母、数字、下划线和点
+var wildcardSafe = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+
+// 白名单匹配，支持 "roles.*" 匹配该表前缀下的任意列，"*" 匹配任意字段
+func matchWhitelist(list []string, field string) bool {
+	for _, w := range list {
+		if w == field {
+			return true
+		}
+		if !strings.HasSuffix(w, "*") || !wildcardSafe.MatchString(field) {
+			continue
+		}
+		if w == "*" {
+			return true
+		}
+		if col, ok := strings.CutPrefix(field, strings.TrimSuffix(w, "*")); ok && col != "" && !strings.Contains(col, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// 字段不可排序的原因，附带可排序的字段，便于调用方定位错误
+func (f *Filter) notSortableReason() string {
+	allowed := f.AlwaysSortable
+	if allowed == nil {
+		allowed = DefaultAlwaysSortable
+	}
+	allowed = unionStrings(allowed, f.Sortable)
+	var exprs []string
+	for name := range f.SortExprs {
+		exprs = append(exprs, name)
+	}
+	sort.Strings(exprs)
+	allowed = unionStrings(allowed, exprs)
+	if len(allowed) == 0 {
+		return "field is not sortable"
+	}
+	return fmt.Sprintf("field is not sortable, allowed: %s", strings.Join(allowed, ", "))
+}
+
+func (f *Filter) isSortable(field string) bool {
+	if strings.TrimSpace(field) == "" {
+		return false
+	}
+	always := f.AlwaysSortable
+	if always == nil {
+		always = DefaultAlwaysSortable
+	}
+	if containsString(always, field) {
+		return true
+	}
+
+	if len(f.Sortable) == 0 {
+		return false
+	}
+
+	return matchWhitelist(f.Sortable, field)
 }
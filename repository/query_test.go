@@ -0,0 +1,561 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type legacy struct {
+	Code string `gorm:"primaryKey"`
+	Name string
+}
+
+type logLine struct {
+	Message string
+}
+
+func TestStableSortUsesPrimaryKey(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{Sort: "name", Sortable: []string{"name"}, StableSort: true}
+	queryDB, err := f.PaginationQueryE(db.Model(&legacy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	queryDB, _ = f.ApplySortAndPaginationE(queryDB)
+	queryDB.Find(&[]legacy{})
+	assertContains(t, rec.last(), `ORDER BY "name" ASC,"legacies"."code" ASC`)
+	assertNotContains(t, rec.last(), `"id"`)
+}
+
+func TestStableSortWithoutPrimaryKey(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{Sort: "message", Sortable: []string{"message"}, StableSort: true}
+	queryDB, err := f.PaginationQueryE(db.Model(&logLine{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	queryDB, _ = f.ApplySortAndPaginationE(queryDB)
+	queryDB.Find(&[]logLine{})
+	assertContains(t, rec.last(), `ORDER BY "message" ASC LIMIT 10`)
+}
+
+func TestStableSortDefaultID(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	sql, err := findSQL(t, db, rec, &Filter{Sort: "name", Sortable: []string{"name"}, StableSort: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, `ORDER BY "name" ASC,"items"."id" ASC`)
+}
+
+func TestNotIn(t *testing.T) {
+	tests := []struct {
+		dialect string
+		filters map[string]interface{}
+		want    string
+	}{
+		{"sqlite", map[string]interface{}{"status": map[string]interface{}{"not_in": []interface{}{1, 2}}}, `"status" NOT IN (1,2)`},
+		{"mysql", map[string]interface{}{"status": map[string]interface{}{"not_in": []interface{}{1, 2}}}, "`status` NOT IN (1,2)"},
+		{"sqlite", map[string]interface{}{"name": map[string]interface{}{"not_in": []string{"a", "b"}}}, `"name" NOT IN ('a','b')`},
+		{"mysql", map[string]interface{}{"status": map[string]interface{}{"not_in": []int{3}}}, "`status` NOT IN (3)"},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, tt.dialect)
+		sql, err := findSQL(t, db, rec, &Filter{Filters: tt.filters})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertContains(t, sql, tt.want)
+	}
+}
+
+func TestNotInEmptySkipped(t *testing.T) {
+	for _, dialect := range []string{"sqlite", "mysql"} {
+		db, rec := dryRunDB(t, dialect)
+		f := &Filter{QueryStr: `{"status":{"not_in":[]}}`}
+		sql, err := findSQL(t, db, rec, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertNotContains(t, sql, "NOT IN")
+	}
+}
+
+func TestLikeAndNotLike(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{
+		Filterable: []string{"name", "status"},
+		QueryStr:   `{"name":{"like":"jo%"},"status":{"not_like":"%archived%"}}`,
+	}
+	sql, err := findSQL(t, db, rec, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, `"name" LIKE 'jo%' ESCAPE '\'`, `"status" NOT LIKE '%archived%' ESCAPE '\'`)
+}
+
+func TestContainsEscapesWildcards(t *testing.T) {
+	tests := []struct {
+		dialect, query, want string
+	}{
+		{"sqlite", `{"name":{"contains":"50%_off"}}`, `"name" LIKE '%50\%\_off%' ESCAPE '\'`},
+		{"mysql", `{"name":{"contains":"50%_off"}}`, "`name` LIKE '%50\\%\\_off%' ESCAPE '\\\\'"},
+		{"sqlite", `{"name":{"starts_with":"a_b"}}`, `"name" LIKE 'a\_b%' ESCAPE '\'`},
+		{"sqlite", `{"name":{"ends_with":"100%"}}`, `"name" LIKE '%100\%' ESCAPE '\'`},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, tt.dialect)
+		sql, err := findSQL(t, db, rec, &Filter{QueryStr: tt.query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertContains(t, sql, tt.want)
+	}
+}
+
+// 按 SQL LIKE 的规则匹配，反斜杠为转义符
+func likeMatch(pattern, s string) bool {
+	if pattern == "" {
+		return s == ""
+	}
+	switch pattern[0] {
+	case '%':
+		for i := 0; i <= len(s); i++ {
+			if likeMatch(pattern[1:], s[i:]) {
+				return true
+			}
+		}
+		return false
+	case '_':
+		return s != "" && likeMatch(pattern[1:], s[1:])
+	case '\\':
+		if len(pattern) > 1 {
+			pattern = pattern[1:]
+		}
+	}
+	return s != "" && s[0] == pattern[0] && likeMatch(pattern[1:], s[1:])
+}
+
+func TestContainsMatchesLiteralOnly(t *testing.T) {
+	pattern := "%" + escapeLike("50%_off") + "%"
+	for s, want := range map[string]bool{
+		"get 50%_off today": true,
+		"50%_off":           true,
+		"500_off":           false,
+		"50%xoff":           false,
+		"50 percent off":    false,
+	} {
+		if got := likeMatch(pattern, s); got != want {
+			t.Errorf("%q LIKE %q = %v, want %v", s, pattern, got, want)
+		}
+	}
+}
+
+func TestRegexpByDialect(t *testing.T) {
+	tests := []struct {
+		dialect, query, want string
+	}{
+		{"mysql", `{"name":{"regexp":"^a.*z$"}}`, "`name` REGEXP '^a.*z$'"},
+		{"mysql", `{"name":{"not_regexp":"^a"}}`, "`name` NOT REGEXP '^a'"},
+		{"postgres", `{"name":{"regexp":"^a.*z$"}}`, `"name" ~ '^a.*z$'`},
+		{"postgres", `{"name":{"not_regexp":"^a"}}`, `"name" !~ '^a'`},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, tt.dialect)
+		sql, err := findSQL(t, db, rec, &Filter{QueryStr: tt.query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertContains(t, sql, tt.want)
+	}
+}
+
+func TestRegexpEmptyPatternSkipped(t *testing.T) {
+	db, rec := dryRunDB(t, "mysql")
+	sql, err := findSQL(t, db, rec, &Filter{QueryStr: `{"name":{"regexp":""}}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNotContains(t, sql, "REGEXP")
+}
+
+func TestGroupParentheses(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{
+			`{"or":[{"and":[{"name":"a"},{"or":[{"status":1},{"status":2}]}]},{"stock":0}]}`,
+			`WHERE (("name" = 'a' AND ("status" = 1 OR "status" = 2)) OR "stock" = 0) AND`,
+		},
+		{
+			`{"name":"a","not":{"or":[{"status":1},{"status":2}]}}`,
+			`NOT ("status" = 1 OR "status" = 2)`,
+		},
+		{
+			`{"not":[{"status":1},{"stock":0}]}`,
+			`NOT ("status" = 1 AND "stock" = 0)`,
+		},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, "sqlite")
+		sql, err := findSQL(t, db, rec, &Filter{QueryStr: tt.query})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertContains(t, sql, tt.want)
+	}
+}
+
+func TestGroupDepthExceeded(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{MaxGroupDepth: 1, QueryStr: `{"or":[{"and":[{"name":"a"}]}]}`}
+	if _, err := findSQL(t, db, rec, f); err == nil {
+		t.Fatal("want error when nesting exceeds MaxGroupDepth")
+	}
+	if sql := rec.last(); sql != "" {
+		t.Errorf("query should not run, got %s", sql)
+	}
+}
+
+// 带 JSON 列的测试模型
+type profile struct {
+	ID       uint
+	Tags     json.RawMessage `gorm:"type:json"`
+	Metadata json.RawMessage `gorm:"type:json"`
+}
+
+func TestJSONContainsByDialect(t *testing.T) {
+	tests := []struct {
+		dialect, query string
+		want           []string
+	}{
+		{"mysql", `{"tags":{"json_contains":"vip"}}`, []string{"JSON_CONTAINS(`tags`, '\"vip\"')"}},
+		{"postgres", `{"tags":{"json_contains":"vip"}}`, []string{`"tags" @> '"vip"'::jsonb`}},
+		{"mysql", `{"metadata.$.region":"eu"}`, []string{"JSON_UNQUOTE(JSON_EXTRACT(`metadata`, '$.region')) = 'eu'"}},
+		{"postgres", `{"metadata.$.geo.region":"eu"}`, []string{`"metadata"->'geo'->>'region' = 'eu'`}},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, tt.dialect)
+		f := &Filter{Filterable: []string{"tags", "metadata"}, QueryStr: tt.query}
+		queryDB, err := f.PaginationQueryE(db.Model(&profile{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		queryDB.Find(&[]profile{})
+		assertContains(t, rec.last(), tt.want...)
+	}
+}
+
+func TestJSONContainsRequiresFilterable(t *testing.T) {
+	db, _ := dryRunDB(t, "mysql")
+	f := &Filter{Filterable: []string{"metadata"}, Strict: true, QueryStr: `{"tags":{"json_contains":"vip"}}`}
+	if _, err := f.PaginationQueryE(db.Model(&profile{})); err == nil {
+		t.Fatal("want error for non-filterable json column")
+	}
+}
+
+func TestFindInSet(t *testing.T) {
+	tests := []struct {
+		dialect, want string
+	}{
+		{"mysql", "FIND_IN_SET('3', `roles`) > 0"},
+		{"sqlite", `(',' || "roles" || ',') LIKE '%,3,%' ESCAPE '\'`},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, tt.dialect)
+		sql, err := findSQL(t, db, rec, &Filter{QueryStr: `{"roles":{"find_in_set":3}}`})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertContains(t, sql, tt.want)
+	}
+}
+
+func TestTupleInFallback(t *testing.T) {
+	query := `{"_tuple_in":{"fields":["name","status"],"values":[["a",1],["b",2]]}}`
+	db, rec := dryRunDB(t, "sqlite")
+	sql, err := findSQL(t, db, rec, &Filter{QueryStr: query})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, `(("name" = 'a' AND "status" = 1) OR ("name" = 'b' AND "status" = 2))`)
+
+	db, rec = dryRunDB(t, "mysql")
+	if sql, err = findSQL(t, db, rec, &Filter{QueryStr: query}); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, "(`name`, `status`) IN (('a', 1), ('b', 2))")
+}
+
+func TestRelativeRange(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	now := func() time.Time { return time.Date(2024, 3, 15, 10, 30, 0, 0, shanghai) }
+	tests := []struct {
+		token      string
+		start, end string
+	}{
+		{"today", "2024-03-15 00:00:00", "2024-03-15 23:59:59.999999"},
+		{"yesterday", "2024-03-14 00:00:00", "2024-03-14 23:59:59.999999"},
+		{"last_7_days", "2024-03-09 00:00:00", "2024-03-15 23:59:59.999999"},
+		{"last_30_days", "2024-02-15 00:00:00", "2024-03-15 23:59:59.999999"},
+		{"this_month", "2024-03-01 00:00:00", "2024-03-31 23:59:59.999999"},
+		{"this_year", "2024-01-01 00:00:00", "2024-12-31 23:59:59.999999"},
+	}
+	for _, tt := range tests {
+		f := &Filter{Now: now, Location: shanghai}
+		start, end, err := f.relativeRange(tt.token)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.token, err)
+		}
+		const layout = "2006-01-02 15:04:05.999999"
+		if got := start.In(shanghai).Format(layout); got != tt.start {
+			t.Errorf("%s start = %s, want %s", tt.token, got, tt.start)
+		}
+		if got := end.In(shanghai).Format(layout); got != tt.end {
+			t.Errorf("%s end = %s, want %s", tt.token, got, tt.end)
+		}
+	}
+}
+
+func TestRelativeRangeSQL(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{
+		Now:      func() time.Time { return time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC) },
+		Location: time.UTC,
+		QueryStr: `{"created_at":{"range":"this_month"}}`,
+	}
+	sql, err := findSQL(t, db, rec, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, `"created_at" BETWEEN '2024-03-01 00:00:00' AND '2024-03-31 23:59:59.999'`)
+}
+
+func TestRelativeRangeUnknownToken(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{QueryStr: `{"created_at":{"range":"last_fortnight"}}`}
+	if _, err := findSQL(t, db, rec, f); err == nil {
+		t.Fatal("want error for unknown range token")
+	}
+	if sql := rec.last(); sql != "" {
+		t.Errorf("query should not run, got %s", sql)
+	}
+}
+
+func TestIgnoreZero(t *testing.T) {
+	filters := func() map[string]interface{} {
+		return map[string]interface{}{"name": "", "status": 0, "stock": 5}
+	}
+	tests := []struct {
+		f       *Filter
+		want    []string
+		notWant []string
+	}{
+		{&Filter{Filters: filters()}, []string{`"name" = ''`, `"status" = 0`, `"stock" = 5`}, nil},
+		{&Filter{Filters: filters(), IgnoreZero: true}, []string{`"status" = 0`, `"stock" = 5`}, []string{`"name"`}},
+		{&Filter{Filters: filters(), IgnoreZero: true, IgnoreZeroNumber: true}, []string{`"stock" = 5`}, []string{`"name"`, `"status"`}},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, "sqlite")
+		sql, err := findSQL(t, db, rec, tt.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertContains(t, sql, tt.want...)
+		assertNotContains(t, sql, tt.notWant...)
+	}
+}
+
+func TestIgnoreZeroDebugRecord(t *testing.T) {
+	db, _ := dryRunDB(t, "sqlite")
+	f := &Filter{Filters: map[string]interface{}{"name": ""}, IgnoreZero: true, Debug: true}
+	if _, err := f.PaginationQueryE(db.Model(&item{})); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.sqlRecords) != 1 || !strings.Contains(f.sqlRecords[0], "SKIP ZERO name") {
+		t.Errorf("want skipped field in debug records, got %v", f.sqlRecords)
+	}
+}
+
+func TestExistsAndNotExists(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{
+		Debug: true,
+		Exists: []ExistsCondition{
+			{Table: "orders", On: "orders.item_id = items.id", Conditions: map[string]interface{}{"status": "paid"}},
+			{Table: "refunds", On: "refunds.item_id = items.id", Negate: true},
+		},
+	}
+	sql, err := findSQL(t, db, rec, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql,
+		`EXISTS (SELECT 1 FROM "orders" WHERE orders.item_id = items.id AND "status" = 'paid')`,
+		`NOT EXISTS (SELECT 1 FROM "refunds" WHERE refunds.item_id = items.id)`)
+	records := strings.Join(f.sqlRecords, "\n")
+	assertContains(t, records, "[EXISTS orders ON", "[NOT EXISTS refunds ON")
+}
+
+func TestArrayPromotion(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string
+		notWant string
+	}{
+		{`{"status":{"eq":[1,2]}}`, `"status" IN (1,2)`, ""},
+		{`{"status":{"neq":[1,2]}}`, `"status" NOT IN (1,2)`, ""},
+		{`{"status":{"eq":[]}}`, `1 = 0`, "IN ()"},
+		{`{"status":{"in":[]}}`, `1 = 0`, "IN ()"},
+		{`{"status":{"neq":[]}}`, "", `"status"`},
+		{`{"status":{"not_in":[]}}`, "", `"status"`},
+		{`{"status":[1,2]}`, `"status" IN (1,2)`, ""},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, "sqlite")
+		sql, err := findSQL(t, db, rec, &Filter{QueryStr: tt.query})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		if tt.want != "" {
+			assertContains(t, sql, tt.want)
+		}
+		if tt.notWant != "" {
+			assertNotContains(t, sql, tt.notWant)
+		}
+	}
+}
+
+func TestHostileKeysNeverReachSQL(t *testing.T) {
+	hostile := []string{
+		`1=1); DROP TABLE users;--`,
+		`name OR 1=1`,
+		`name;`,
+		"name`",
+		`"name"`,
+		`items.name.x`,
+		`(select 1)`,
+	}
+	for _, key := range hostile {
+		raw, _ := json.Marshal(map[string]interface{}{key: 1})
+		for _, strict := range []bool{false, true} {
+			for _, f := range []*Filter{
+				{QueryStr: string(raw)},
+				{QueryStr: `{"name":{"eq":1}}`, Sort: key},
+				{Filters: map[string]interface{}{key: map[string]interface{}{"gt": 1}}},
+			} {
+				db, rec := dryRunDB(t, "sqlite")
+				f.Strict = strict
+				// 非严格模式下不可排序的字段静默忽略，严格模式下一律报错
+				if _, err := findSQL(t, db, rec, f); err == nil && strict {
+					t.Errorf("key %q: want error", key)
+				}
+				for _, sql := range rec.all() {
+					assertNotContains(t, sql, key)
+				}
+			}
+		}
+	}
+}
+
+func TestSortByJoinedColumn(t *testing.T) {
+	joins := []JoinConfig{{Table: "roles", On: "roles.id = items.status", JoinType: "left"}}
+	tests := []struct {
+		dialect  string
+		sortable []string
+		sort     string
+		want     string
+	}{
+		{"mysql", []string{"roles.name"}, "-roles.name", "ORDER BY `roles`.`name` DESC"},
+		{"postgres", []string{"roles.*"}, "roles.level,-roles.name", `ORDER BY "roles"."level" ASC,"roles"."name" DESC`},
+	}
+	for _, tt := range tests {
+		db, rec := dryRunDB(t, tt.dialect)
+		sql, err := findSQL(t, db, rec, &Filter{Joins: joins, Sortable: tt.sortable, Sort: tt.sort, Strict: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertContains(t, sql, tt.want)
+	}
+}
+
+func TestSortByJoinedColumnNotSortable(t *testing.T) {
+	db, rec := dryRunDB(t, "mysql")
+	f := &Filter{
+		Joins:    []JoinConfig{{Table: "roles", On: "roles.id = items.status"}},
+		Sortable: []string{"roles.*"},
+		Sort:     "users.name",
+		Strict:   true,
+	}
+	if _, err := findSQL(t, db, rec, f); err == nil {
+		t.Fatal("want error for column of a table not covered by roles.*")
+	}
+}
+
+func TestSearchQuotesColumns(t *testing.T) {
+	db, rec := dryRunDB(t, "mysql")
+	sql, err := findSQL(t, db, rec, &Filter{Search: "jo", SearchFields: []string{"name", "items.status"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, "(`name` LIKE '%jo%' ESCAPE '\\\\' OR `items`.`status` LIKE '%jo%' ESCAPE '\\\\')")
+
+	db, rec = dryRunDB(t, "mysql")
+	f := &Filter{Search: "jo", SearchFields: []string{"name", "status"}, SearchMode: "match"}
+	if sql, err = findSQL(t, db, rec, f); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, "MATCH(`name`, `status`) AGAINST")
+}
+
+func TestInSubquery(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{
+		QueryStr: `{"_in_subquery":{"member":{"role":"owner"}}}`,
+		InSubqueries: []InSubquery{{
+			Name: "member", Column: "items.id", Table: "memberships", SelectColumn: "item_id",
+			Conditions: map[string]interface{}{"active": 1},
+			Filterable: []string{"role"},
+		}},
+	}
+	sql, err := findSQL(t, db, rec, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, `items.id IN (SELECT item_id FROM "memberships" WHERE "active" = 1 AND "role" = 'owner')`)
+}
+
+func TestInSubqueryRejectsConditionsWithoutFilterable(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{
+		QueryStr: `{"_in_subquery":{"member":{"secret_token":{"like":"a%"}}}}`,
+		InSubqueries: []InSubquery{{
+			Name: "member", Column: "items.id", Table: "memberships", SelectColumn: "item_id",
+		}},
+	}
+	_, err := findSQL(t, db, rec, f)
+	var fe FilterError
+	if !errors.As(err, &fe) || fe.Field != "_in_subquery" {
+		t.Fatalf("want filter error on _in_subquery, got %v", err)
+	}
+	if sql := rec.last(); sql != "" {
+		t.Errorf("query should not run, got %s", sql)
+	}
+}
+
+func TestPaginationQueryLenient(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{QueryStr: `{"name":`, Filters: map[string]interface{}{"status": 1}}
+	var items []item
+	if err := f.PaginationQuery(db.Model(&item{})).Find(&items).Error; err != nil {
+		t.Fatalf("PaginationQuery should skip invalid conditions, got %v", err)
+	}
+	assertContains(t, rec.last(), `"status" = 1`)
+
+	if _, err := f.PaginationQueryE(db.Model(&item{})); err == nil {
+		t.Fatal("PaginationQueryE should report invalid json")
+	}
+}
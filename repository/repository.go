@@ -5,34 +5,198 @@ import (
 )
 
 type Repository[T any] interface {
-	GetInfoById(id uint) (*T, error)
+	GetInfoById(id uint, opts ...QueryOption) (*T, error)
+	ExistsById(id uint) (bool, error)
+	ExistsByFilter(f *Filter) (bool, error)
 	Create(m *T) error
+	CreateBatch(items []T) (int64, error)
+	Save(m *T, opts ...SaveOption) error
+	Upsert(m *T, conflictColumns []string, updateColumns []string) error
+	UpsertBatch(items []T, conflictColumns []string, updateColumns []string) (int64, error)
+	FirstOrCreate(where map[string]interface{}, attrs *T) (*T, bool, error)
+	BulkUpdateColumn(column string, values map[uint]interface{}) (int64, error)
 	UpdateById(id uint, updates map[string]interface{}) error
+	UpdateByIds(ids []uint, updates map[string]interface{}) (int64, error)
 	DeleteById(id uint) error
 	SoftDeleteById(id uint) error
+	DeleteByIds(ids []uint) (int64, error)
+	ForceDeleteById(id uint) error
+	SoftDeleteByIds(ids []uint) (int64, error)
 	ListPagination(f *Filter) ([]T, int64, int, int, error)
+	ListPage(f *Filter) (*PageResult[T], error)
 	ListByFilter(f *Filter) ([]T, error)
+	Count(f *Filter) (int64, error)
 	GetDB() *gorm.DB
 }
 
 type baseRepository[T any] struct {
-	db *gorm.DB
+	db        *gorm.DB
+	opts      repositoryOptions
+	whitelist *modelWhitelist
 }
 
-func NewBaseRepository[T any](db *gorm.DB) Repository[T] {
-	return &baseRepository[T]{db: db}
+// RepositoryOption 仓储配置项
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	omitFields     []string
+	modelWhitelist bool
+	baseConditions map[string]interface{}
+	protected      []string
+	updatable      []string
+	strictUpdates  bool
+	bulkChunkSize  int
+}
+
+// WithDefaultOmit 列表查询默认排除的列（如大字段），Filter 指定了 OmitFields 时以 Filter 为准
+func WithDefaultOmit(fields ...string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.omitFields = fields
+	}
+}
+
+// WithModelWhitelist Filter 未设置 Filterable/Sortable 时，使用从模型结构解析出的白名单（见 FilterableFromModel）
+func WithModelWhitelist() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.modelWhitelist = true
+	}
+}
+
+// WithBaseConditions 列表查询始终附加的基础条件（如 {"visibility": "public"}），与 Filter.BaseConditions 以 AND 合并
+func WithBaseConditions(conds map[string]interface{}) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.baseConditions = conds
+	}
+}
+
+// WithProtectedColumns UpdateById、UpdateByIds 不允许修改的列，在 DefaultProtectedColumns 的基础上追加
+func WithProtectedColumns(columns ...string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.protected = append(o.protected, columns...)
+	}
+}
+
+// WithUpdatable UpdateById、UpdateByIds 可更新的列，白名单以外的列被忽略；未设置时不限制
+func WithUpdatable(columns ...string) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.updatable = columns
+	}
+}
+
+// WithStrictUpdates UpdateById、UpdateByIds 包含白名单以外的列时返回 ErrColumnNotUpdatable，而不是忽略
+func WithStrictUpdates() RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.strictUpdates = true
+	}
 }
 
-func (r *baseRepository[T]) GetInfoById(id uint) (*T, error) {
-	return GetInfoById[T](r.db, id)
+// WithBulkUpdateChunkSize BulkUpdateColumn 每条语句更新的最大行数，未设置时使用 DefaultBulkUpdateChunkSize
+func WithBulkUpdateChunkSize(size int) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.bulkChunkSize = size
+	}
+}
+
+func NewBaseRepository[T any](db *gorm.DB, opts ...RepositoryOption) Repository[T] {
+	r := &baseRepository[T]{db: db}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	if r.opts.modelWhitelist {
+		// 预先解析并缓存，解析失败时不设置默认白名单
+		if wl, err := cachedModelWhitelist[T](db.NamingStrategy); err == nil {
+			r.whitelist = &wl
+		}
+	}
+	return r
+}
+
+// 列表查询前应用仓储的默认配置，返回 f 的副本，f 本身不变，可重复用于多次查询
+func (r *baseRepository[T]) prepareFilter(f *Filter) *Filter {
+	if f == nil {
+		f = &Filter{}
+	} else {
+		f = f.Clone()
+	}
+	if len(f.OmitFields) == 0 && len(r.opts.omitFields) > 0 {
+		f.OmitFields = r.opts.omitFields
+	}
+	if len(r.opts.baseConditions) > 0 {
+		f.BaseConditions = mergeConditions(cloneConditions(r.opts.baseConditions), f.BaseConditions)
+	}
+	if r.whitelist != nil {
+		if len(f.Filterable) == 0 {
+			f.Filterable = r.whitelist.filterable
+		}
+		if len(f.Sortable) == 0 {
+			f.Sortable = r.whitelist.sortable
+		}
+	}
+	return f
+}
+
+func (r *baseRepository[T]) GetInfoById(id uint, opts ...QueryOption) (*T, error) {
+	return GetInfoById[T](r.db, id, opts...)
+}
+
+func (r *baseRepository[T]) ExistsById(id uint) (bool, error) {
+	return ExistsById[T](r.db, id)
+}
+
+func (r *baseRepository[T]) ExistsByFilter(f *Filter) (bool, error) {
+	return ExistsByFilter[T](r.db, r.prepareFilter(f))
 }
 
 func (r *baseRepository[T]) Create(m *T) error {
 	return Created[T](r.db, m)
 }
 
+func (r *baseRepository[T]) CreateBatch(items []T) (int64, error) {
+	return BatchCreate[T](r.db, items, DefaultBatchSize)
+}
+
+func (r *baseRepository[T]) Save(m *T, opts ...SaveOption) error {
+	return Save[T](r.db, m, opts...)
+}
+
+func (r *baseRepository[T]) Upsert(m *T, conflictColumns []string, updateColumns []string) error {
+	return Upsert[T](r.db, m, conflictColumns, updateColumns)
+}
+
+func (r *baseRepository[T]) UpsertBatch(items []T, conflictColumns []string, updateColumns []string) (int64, error) {
+	return UpsertBatch[T](r.db, items, conflictColumns, updateColumns)
+}
+
+func (r *baseRepository[T]) FirstOrCreate(where map[string]interface{}, attrs *T) (*T, bool, error) {
+	return FirstOrCreate[T](r.db, where, attrs)
+}
+
+func (r *baseRepository[T]) BulkUpdateColumn(column string, values map[uint]interface{}) (int64, error) {
+	return bulkUpdateColumn[T](r.db, column, values, r.opts.bulkChunkSize)
+}
+
 func (r *baseRepository[T]) UpdateById(id uint, updates map[string]interface{}) error {
-	return UpdateByIdWithMap[T](r.db, id, updates)
+	updates, err := r.updatableColumns(updates)
+	if err != nil {
+		return err
+	}
+	return updateByIdWithMap[T](r.db, id, updates, unionStrings(DefaultProtectedColumns, r.opts.protected))
+}
+
+func (r *baseRepository[T]) UpdateByIds(ids []uint, updates map[string]interface{}) (int64, error) {
+	updates, err := r.updatableColumns(updates)
+	if err != nil {
+		return 0, err
+	}
+	return updateByIdsWithMap[T](r.db, ids, updates, unionStrings(DefaultProtectedColumns, r.opts.protected))
+}
+
+// 按 WithUpdatable 的白名单筛选更新的列，未设置白名单时原样返回
+func (r *baseRepository[T]) updatableColumns(updates map[string]interface{}) (map[string]interface{}, error) {
+	if len(r.opts.updatable) == 0 {
+		return updates, nil
+	}
+	return allowedUpdates(updates, r.opts.updatable, r.opts.strictUpdates)
 }
 
 func (r *baseRepository[T]) DeleteById(id uint) error {
@@ -43,12 +207,36 @@ func (r *baseRepository[T]) SoftDeleteById(id uint) error {
 	return SoftDeleteById[T](r.db, id)
 }
 
+func (r *baseRepository[T]) DeleteByIds(ids []uint) (int64, error) {
+	return DeleteByIds[T](r.db, ids)
+}
+
+func (r *baseRepository[T]) SoftDeleteByIds(ids []uint) (int64, error) {
+	return SoftDeleteByIds[T](r.db, ids)
+}
+
+func (r *baseRepository[T]) ForceDeleteById(id uint) error {
+	return ForceDeleteById[T](r.db, id)
+}
+
 func (r *baseRepository[T]) ListPagination(f *Filter) ([]T, int64, int, int, error) {
-	return QueryWithPagination[T](r.db, f)
+	return QueryWithPagination[T](r.db, r.prepareFilter(f))
+}
+
+func (r *baseRepository[T]) ListPage(f *Filter) (*PageResult[T], error) {
+	return QueryPage[T](r.db, r.prepareFilter(f))
 }
 
 func (r *baseRepository[T]) ListByFilter(f *Filter) ([]T, error) {
-	return QueryWithFilter[T](r.db, f)
+	return QueryWithFilter[T](r.db, r.prepareFilter(f))
+}
+
+// Count f 为 nil 时统计全部记录（仍应用仓储的基础条件）
+func (r *baseRepository[T]) Count(f *Filter) (int64, error) {
+	if f == nil {
+		f = &Filter{}
+	}
+	return CountByFilter[T](r.db, r.prepareFilter(f))
 }
 
 func (r *baseRepository[T]) GetDB() *gorm.DB {
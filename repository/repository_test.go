@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestRepositoryReusesFilter(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	repo := NewBaseRepository[item](db,
+		WithBaseConditions(map[string]interface{}{"is_deleted": 0}),
+		WithDefaultOmit("stock"),
+		WithModelWhitelist())
+	f := &Filter{Filters: map[string]interface{}{"status": 1}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.Count(f); err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(rec.last(), `"is_deleted" = 0`); n != 1 {
+			t.Errorf("call %d: base condition applied %d times\nsql: %s", i+1, n, rec.last())
+		}
+	}
+	if f.BaseConditions != nil || f.OmitFields != nil || f.Filterable != nil || f.Sortable != nil {
+		t.Errorf("repository defaults leaked into the caller's filter: %+v", f)
+	}
+}
+
+func TestRepositoryCountNilFilter(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	repo := NewBaseRepository[item](db, WithBaseConditions(map[string]interface{}{"is_deleted": 0}))
+	if _, err := repo.Count(nil); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), `"is_deleted" = 0`)
+}
+
+func TestRepositoryUpdateByIdsWhitelist(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	repo := NewBaseRepository[item](db, WithUpdatable("name"))
+	if _, err := repo.UpdateByIds([]uint{1, 2}, map[string]interface{}{"name": "x", "stock": 9}); err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, rec.last(), `"name"='x'`, `id IN (1,2)`)
+	assertNotContains(t, rec.last(), `"stock"`)
+
+	if _, err := repo.UpdateByIds([]uint{1}, map[string]interface{}{"stock": 9}); !errors.Is(err, ErrEmptyUpdates) {
+		t.Fatalf("want ErrEmptyUpdates, got %v", err)
+	}
+
+	strict := NewBaseRepository[item](db, WithUpdatable("name"), WithStrictUpdates())
+	if _, err := strict.UpdateByIds([]uint{1}, map[string]interface{}{"name": "x", "stock": 9}); !errors.Is(err, ErrColumnNotUpdatable) {
+		t.Fatalf("want ErrColumnNotUpdatable, got %v", err)
+	}
+}
+
+func TestRepositoryBulkUpdateChunkSize(t *testing.T) {
+	values := map[uint]interface{}{1: 10, 2: 20, 3: 30, 4: 40, 5: 50}
+	for _, tt := range []struct {
+		opts []RepositoryOption
+		want int
+	}{
+		{nil, 1},
+		{[]RepositoryOption{WithBulkUpdateChunkSize(2)}, 3},
+	} {
+		db, rec := dryRunDB(t, "sqlite")
+		db = db.Session(&gorm.Session{SkipDefaultTransaction: true})
+		repo := NewBaseRepository[item](db, tt.opts...)
+		if _, err := repo.BulkUpdateColumn("stock", values); err != nil {
+			t.Fatal(err)
+		}
+		sqls := rec.all()
+		if len(sqls) != tt.want {
+			t.Fatalf("want %d statements, got %d: %v", tt.want, len(sqls), sqls)
+		}
+		assertContains(t, sqls[0], `UPDATE "items" SET "stock"=CASE id WHEN 1 THEN 10`)
+	}
+}
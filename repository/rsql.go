@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RSQL 比较运算符到内部运算符的映射
+var rsqlOperators = map[string]string{
+	"==":    "eq",
+	"!=":    "neq",
+	"=gt=":  "gt",
+	"=ge=":  "gte",
+	"=lt=":  "lt",
+	"=le=":  "lte",
+	"=in=":  "in",
+	"=out=": "not_in",
+}
+
+// ParseRSQL 将 RSQL/FIQL 查询（如 name==jo*;age=ge=18,(status==active)）解析为与 QueryStr 相同的条件 map。
+// 支持 == != =gt= =ge= =lt= =le= =in= =out=，";" 表示 AND，"," 表示 OR，括号分组，
+// == / != 的值包含 * 时转为 like / not_like，* 对应 %，值中的 % _ \ 按字面量匹配
+func ParseRSQL(s string) (map[string]interface{}, error) {
+	p := &rsqlParser{input: s}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+	return cond, nil
+}
+
+type rsqlParser struct {
+	input string
+	pos   int
+}
+
+func (p *rsqlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("rsql: at position %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *rsqlParser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// or := and ("," and)*
+func (p *rsqlParser) parseOr() (map[string]interface{}, error) {
+	return p.parseList(',', "or", p.parseAnd)
+}
+
+// and := constraint (";" constraint)*
+func (p *rsqlParser) parseAnd() (map[string]interface{}, error) {
+	return p.parseList(';', "and", p.parseConstraint)
+}
+
+func (p *rsqlParser) parseList(sep byte, logic string, next func() (map[string]interface{}, error)) (map[string]interface{}, error) {
+	var items []interface{}
+	for {
+		cond, err := next()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, cond)
+		if p.peek() != sep {
+			break
+		}
+		p.pos++
+	}
+	if len(items) == 1 {
+		return items[0].(map[string]interface{}), nil
+	}
+	return map[string]interface{}{logic: items}, nil
+}
+
+// constraint := "(" or ")" | selector operator arguments
+func (p *rsqlParser) parseConstraint() (map[string]interface{}, error) {
+	if p.peek() == '(' {
+		p.pos++
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		return cond, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune("=!<>~();,'\" ", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	selector := p.input[start:p.pos]
+	if selector == "" {
+		return nil, p.errorf("missing selector")
+	}
+
+	op, err := p.parseOperator()
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	if p.peek() == '(' {
+		p.pos++
+		var list []interface{}
+		for {
+			v, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			if p.peek() != ',' {
+				break
+			}
+			p.pos++
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		value = list
+	} else {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+
+	// == / != 的值包含通配符时转为 like / not_like，先转义值中的 LIKE 通配符
+	if str, ok := value.(string); ok && strings.Contains(str, "*") && (op == "eq" || op == "neq") {
+		value = strings.ReplaceAll(escapeLike(str), "*", "%")
+		if op == "eq" {
+			op = "like"
+		} else {
+			op = "not_like"
+		}
+	}
+	return map[string]interface{}{selector: map[string]interface{}{op: value}}, nil
+}
+
+func (p *rsqlParser) parseOperator() (string, error) {
+	rest := p.input[p.pos:]
+	if strings.HasPrefix(rest, "==") || strings.HasPrefix(rest, "!=") {
+		p.pos += 2
+		return rsqlOperators[rest[:2]], nil
+	}
+	if strings.HasPrefix(rest, "=") {
+		if end := strings.IndexByte(rest[1:], '='); end >= 0 {
+			if op, ok := rsqlOperators[rest[:end+2]]; ok {
+				p.pos += end + 2
+				return op, nil
+			}
+		}
+	}
+	return "", p.errorf("unknown operator")
+}
+
+// 值：引号包裹的字符串，或不含保留字符的字符串
+func (p *rsqlParser) parseValue() (string, error) {
+	if q := p.peek(); q == '"' || q == '\'' {
+		p.pos++
+		var b strings.Builder
+		for p.pos < len(p.input) {
+			c := p.input[p.pos]
+			switch {
+			case c == '\\' && p.pos+1 < len(p.input):
+				b.WriteByte(p.input[p.pos+1])
+				p.pos += 2
+			case c == q:
+				p.pos++
+				return b.String(), nil
+			default:
+				b.WriteByte(c)
+				p.pos++
+			}
+		}
+		return "", p.errorf("unterminated string")
+	}
+	start := p.pos
+	for p.pos < len(p.input) && !strings.ContainsRune("();,'\" ", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("missing value")
+	}
+	return p.input[start:p.pos], nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRSQL(t *testing.T) {
+	eq := func(field string, op string, v interface{}) map[string]interface{} {
+		return map[string]interface{}{field: map[string]interface{}{op: v}}
+	}
+	cases := []struct {
+		in   string
+		want map[string]interface{}
+	}{
+		{"name==jo", eq("name", "eq", "jo")},
+		{"name!=jo", eq("name", "neq", "jo")},
+		{"age=gt=18", eq("age", "gt", "18")},
+		{"age=ge=18", eq("age", "gte", "18")},
+		{"age=lt=18", eq("age", "lt", "18")},
+		{"age=le=18", eq("age", "lte", "18")},
+		{"status=in=(a,b)", eq("status", "in", []interface{}{"a", "b"})},
+		{"status=out=(a)", eq("status", "not_in", []interface{}{"a"})},
+		// ";" 优先于 ","
+		{"a==1;b==2,c==3", map[string]interface{}{"or": []interface{}{
+			map[string]interface{}{"and": []interface{}{eq("a", "eq", "1"), eq("b", "eq", "2")}},
+			eq("c", "eq", "3"),
+		}}},
+		{"a==1;(b==2,c==3)", map[string]interface{}{"and": []interface{}{
+			eq("a", "eq", "1"),
+			map[string]interface{}{"or": []interface{}{eq("b", "eq", "2"), eq("c", "eq", "3")}},
+		}}},
+		{`name=="jo, (jr);"`, eq("name", "eq", "jo, (jr);")},
+		{`name=='it\'s'`, eq("name", "eq", "it's")},
+		{"name==jo*", eq("name", "like", "jo%")},
+		{"name!=*jo*", eq("name", "not_like", "%jo%")},
+		// 值中的 LIKE 通配符按字面量匹配
+		{"name==a_b*", eq("name", "like", `a\_b%`)},
+		{`name=="50%*"`, eq("name", "like", `50\%%`)},
+		{"name==a_b", eq("name", "eq", "a_b")},
+	}
+	for _, c := range cases {
+		got, err := ParseRSQL(c.in)
+		if err != nil {
+			t.Errorf("ParseRSQL(%q): %v", c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseRSQL(%q) = %#v, want %#v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseRSQLMalformed(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"name",
+		"==jo",
+		"name=xx=1",
+		"name==",
+		"name==jo;",
+		"(name==jo",
+		"name==jo)",
+		"status=in=(a,b",
+		`name=="jo`,
+	} {
+		if got, err := ParseRSQL(in); err == nil {
+			t.Errorf("ParseRSQL(%q) = %v, want error", in, got)
+		}
+	}
+}
+
+func TestRSQLWildcardSQL(t *testing.T) {
+	db, rec := dryRunDB(t, "sqlite")
+	f := &Filter{Filterable: []string{"name"}, QuerySyntax: "rsql", QueryStr: "name==a_b*"}
+	sql, err := findSQL(t, db, rec, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertContains(t, sql, `"name" LIKE 'a\_b%' ESCAPE '\'`)
+	if !likeMatch(`a\_b%`, "a_bc") || likeMatch(`a\_b%`, "axbc") {
+		t.Error("escaped pattern should only match a literal underscore")
+	}
+}
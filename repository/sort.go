@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 排序项，如 "-last_active_at:nulls_last"、"name desc:ci"
+type sortTerm struct {
+	field string
+	order string // ASC 或 DESC
+	nulls string // 空、nulls_first 或 nulls_last
+	ci    bool   // 不区分大小写
+}
+
+// 调试记录中的排序方向和修饰
+func (t sortTerm) modifiers() string {
+	parts := []string{t.order}
+	if t.nulls != "" {
+		parts = append(parts, strings.ToUpper(t.nulls))
+	}
+	if t.ci {
+		parts = append(parts, "CI")
+	}
+	return strings.Join(parts, " ")
+}
+
+// 解析单个排序项，方向支持 "-name"、"+name"、"name desc"、"name:desc" 等写法（asc/desc 不区分大小写），
+// ":nulls_first" / ":nulls_last" 后缀指定 NULL 的位置，":ci" 后缀不区分大小写排序，其余写法返回错误
+func parseSortTerm(s string) (sortTerm, error) {
+	t := sortTerm{order: "ASC"}
+	head, mods, _ := strings.Cut(s, ":")
+	parts := strings.Fields(head)
+	if len(parts) == 0 || len(parts) > 2 {
+		return t, fmt.Errorf("invalid sort term %q", s)
+	}
+	field, order := parts[0], ""
+	switch {
+	case strings.HasPrefix(field, "-"):
+		order, field = "DESC", field[1:]
+	case strings.HasPrefix(field, "+"):
+		order, field = "ASC", field[1:]
+	}
+	if field == "" {
+		return t, fmt.Errorf("invalid sort term %q", s)
+	}
+	setOrder := func(token string) error {
+		dir := strings.ToUpper(token)
+		if dir != "ASC" && dir != "DESC" {
+			return fmt.Errorf("unknown sort direction %q", token)
+		}
+		if order != "" && order != dir {
+			return fmt.Errorf("conflicting sort direction in %q", s)
+		}
+		order = dir
+		return nil
+	}
+	if len(parts) == 2 {
+		if err := setOrder(parts[1]); err != nil {
+			return t, err
+		}
+	}
+	if mods != "" {
+		for _, mod := range strings.Split(mods, ":") {
+			switch strings.ToLower(mod) {
+			case "asc", "desc":
+				if err := setOrder(mod); err != nil {
+					return t, err
+				}
+			case "nulls_first", "nulls_last":
+				if t.nulls != "" {
+					return t, fmt.Errorf("duplicate sort modifier %q", mod)
+				}
+				t.nulls = strings.ToLower(mod)
+			case "ci":
+				t.ci = true
+			default:
+				return t, fmt.Errorf("unknown sort modifier %q", mod)
+			}
+		}
+	}
+	if order != "" {
+		t.order = order
+	}
+	t.field = field
+	return t, nil
+}
+
+// 生成 ORDER BY 表达式，column 需已校验并引用。
+// ":ci" 时 MySQL 配置了 Collation 使用 COLLATE，否则使用 LOWER(column)；
+// Postgres 原生支持 NULLS FIRST / NULLS LAST，其余方言先按 column IS NULL 排序模拟
+func (f *Filter) orderExpr(db *gorm.DB, column string, t sortTerm) string {
+	if t.ci {
+		if dialectName(db) == "mysql" && collationPattern.MatchString(f.Collation) {
+			column = fmt.Sprintf("%s COLLATE %s", column, f.Collation)
+		} else {
+			column = fmt.Sprintf("LOWER(%s)", column)
+		}
+	}
+	expr := fmt.Sprintf("%s %s", column, t.order)
+	if t.nulls == "" {
+		return expr
+	}
+	if dialectName(db) == "postgres" {
+		return expr + " " + strings.ToUpper(strings.ReplaceAll(t.nulls, "_", " "))
+	}
+	if t.nulls == "nulls_last" {
+		return fmt.Sprintf("%s IS NULL, %s", column, expr)
+	}
+	return fmt.Sprintf("%s IS NOT NULL, %s", column, expr)
+}
+
+// 排序规则名称，如 utf8mb4_0900_ai_ci
+var collationPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// 随机排序：MySQL 使用 RAND(seed)，Postgres 使用 random() 并通过 setseed 设置种子，其余方言使用 random()，不支持种子
+func (f *Filter) applyRandomOrder(db *gorm.DB) *gorm.DB {
+	switch dialectName(db) {
+	case "mysql":
+		if f.RandomSeed != 0 {
+			db = db.Order(clause.OrderBy{Expression: clause.Expr{SQL: "RAND(?)", Vars: []interface{}{f.RandomSeed}}})
+		} else {
+			db = db.Order("RAND()")
+		}
+	case "postgres":
+		if f.RandomSeed != 0 {
+			// setseed 只接受 [-1, 1] 的值，在 FROM 中先于 random() 执行
+			seed := float64(f.RandomSeed%math.MaxInt32) / math.MaxInt32
+			db = db.Joins("CROSS JOIN (SELECT setseed(?)) AS random_seed", seed)
+		}
+		db = db.Order("random()")
+	default:
+		db = db.Order("random()")
+	}
+	f.recordSQL("ORDER RANDOM", f.RandomSeed)
+	return db
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Validate 在访问数据库前校验筛选条件：QueryStr 能否解析、字段是否可筛选、运算符是否已知、
+// between 数组长度、排序字段是否可排序、分页参数是否合法（含 MaxOffset），一次返回全部问题。
+// model 为模型指针（如 &User{}），用于按字段类型校验条件值，可为 nil。校验不会修改 f
+func (f *Filter) Validate(model interface{}) []FilterError {
+	v := *f
+	v.Debug = false
+	v.Strict = true
+	v.sqlRecords = nil
+
+	db := validateDB()
+	if model != nil {
+		db = db.Model(model)
+	}
+	queryDB, _ := v.PaginationQueryE(db)
+
+	if f.Page < 0 {
+		v.errs = append(v.errs, FilterError{Field: "page", Reason: "must not be negative"})
+	}
+	if f.PageSize < 0 {
+		v.errs = append(v.errs, FilterError{Field: "page_size", Reason: "must not be negative"})
+	}
+	// 字段错误已记录在 v.errs 中，这里只补充页码超出 MaxOffset 的错误
+	_, err := v.ApplySortAndPaginationE(queryDB)
+	var pageErr *PageOutOfRangeError
+	if errors.As(err, &pageErr) {
+		v.errs = append(v.errs, FilterError{Field: "page", Reason: fmt.Sprintf("out of range, max page %d", pageErr.MaxPage)})
+	}
+	return v.errs
+}
+
+var (
+	validateOnce sync.Once
+	validateConn *gorm.DB
+)
+
+// 校验使用的 DryRun 连接，不连接真实数据库
+func validateDB() *gorm.DB {
+	validateOnce.Do(func() {
+		validateConn, _ = gorm.Open(validateDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	})
+	return validateConn.Session(&gorm.Session{NewDB: true})
+}
+
+// validateDialector 仅用于构建条件的空方言，名称为空，方言相关的运算符不做限制
+type validateDialector struct{}
+
+func (validateDialector) Name() string                    { return "" }
+func (validateDialector) Initialize(*gorm.DB) error       { return nil }
+func (validateDialector) Migrator(*gorm.DB) gorm.Migrator { return nil }
+func (validateDialector) DataTypeOf(*schema.Field) string { return "" }
+func (validateDialector) DefaultValueOf(*schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+func (validateDialector) BindVarTo(w clause.Writer, _ *gorm.Statement, _ interface{}) {
+	_ = w.WriteByte('?')
+}
+func (validateDialector) QuoteTo(w clause.Writer, s string) { _, _ = w.WriteString(s) }
+func (validateDialector) Explain(sql string, _ ...interface{}) string {
+	return sql
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"testing"
+)
+
+func TestValidatePageOutOfRange(t *testing.T) {
+	f := &Filter{Page: 11, PageSize: 10, MaxOffset: 50}
+	errs := f.Validate(&item{})
+	if len(errs) != 1 || errs[0].Field != "page" {
+		t.Fatalf("want one page error, got %v", errs)
+	}
+
+	f.Page = 6
+	if errs := f.Validate(&item{}); len(errs) != 0 {
+		t.Errorf("want no errors within MaxOffset, got %v", errs)
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	f := &Filter{
+		Filterable: []string{"name"},
+		Sortable:   []string{"name"},
+		QueryStr:   `{"name":{"bogus":1},"status":1}`,
+		Sort:       "stock",
+		Page:       -1,
+	}
+	fields := map[string]bool{}
+	for _, e := range f.Validate(&item{}) {
+		fields[e.Field] = true
+	}
+	for _, want := range []string{"name", "status", "stock", "page"} {
+		if !fields[want] {
+			t.Errorf("missing error for %s, got %v", want, fields)
+		}
+	}
+}
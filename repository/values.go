@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FilterOption 构建 Filter 的配置项
+type FilterOption func(*Filter)
+
+// WithFilterable 设置可供筛选的字段
+func WithFilterable(fields ...string) FilterOption {
+	return func(f *Filter) {
+		f.Filterable = fields
+	}
+}
+
+// WithSortable 设置可供排序的字段
+func WithSortable(fields ...string) FilterOption {
+	return func(f *Filter) {
+		f.Sortable = fields
+	}
+}
+
+// WithSearchFields 设置参数 q 全局搜索匹配的列
+func WithSearchFields(fields ...string) FilterOption {
+	return func(f *Filter) {
+		f.SearchFields = fields
+	}
+}
+
+// filter[字段] 或 filter[字段][运算符]
+var filterParamPattern = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// NewFilterFromValues 从 url 查询参数构建 Filter，支持：
+//
+//	filter[name]=jo             等于
+//	filter[name][like]=jo%      运算符，同 QueryStr 中的写法
+//	filter[status]=a&filter[status]=b  重复参数转为 IN 列表
+//	sort=-created_at,name  page=2  page_size=20  q=关键字（全局搜索，需配合 WithSearchFields）
+//
+// 字段仍受 Filterable/Sortable 约束，页码等参数格式错误、方括号不匹配或条件数超过 MaxConditions 时返回错误
+func NewFilterFromValues(v url.Values, opts ...FilterOption) (*Filter, error) {
+	f := &Filter{Filters: make(map[string]interface{})}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	for key, values := range v {
+		switch key {
+		case "sort":
+			f.Sort = strings.Join(values, ",")
+			continue
+		case "page", "page_size":
+			n, err := strconv.Atoi(values[len(values)-1])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid %s %q", key, values[len(values)-1])
+			}
+			if key == "page" {
+				f.Page = n
+			} else {
+				f.PageSize = n
+			}
+			continue
+		case "q":
+			f.Search = values[len(values)-1]
+			continue
+		}
+		// 只处理 filter[...] 形式的参数，filterMode 等其他参数忽略
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+		m := filterParamPattern.FindStringSubmatch(key)
+		if m == nil {
+			return nil, fmt.Errorf("malformed filter parameter %q", key)
+		}
+		field, op := m[1], m[2]
+		value := paramValue(values)
+		existing, exists := f.Filters[field]
+		if op == "" {
+			if exists {
+				return nil, fmt.Errorf("filter parameter %q conflicts with operators on the same field", key)
+			}
+			f.Filters[field] = value
+			continue
+		}
+		conds, ok := existing.(map[string]interface{})
+		if !ok {
+			if exists {
+				return nil, fmt.Errorf("filter parameter %q conflicts with filter[%s]", key, field)
+			}
+			conds = make(map[string]interface{})
+			f.Filters[field] = conds
+		}
+		conds[op] = value
+	}
+	// 与 QueryStr 相同的条件数上限
+	if max := f.maxConditions(); max >= 0 && countConditions(f.Filters) > max {
+		return nil, fmt.Errorf("too many filter parameters, max %d", max)
+	}
+	return f, nil
+}
+
+// 单个值原样返回，重复参数转为列表
+func paramValue(values []string) interface{} {
+	if len(values) == 1 {
+		return values[0]
+	}
+	list := make([]interface{}, len(values))
+	for i, s := range values {
+		list[i] = s
+	}
+	return list
+}
+
+// WithMaxConditions 设置最多可包含的条件数，同 Filter.MaxConditions
+func WithMaxConditions(n int) FilterOption {
+	return func(f *Filter) {
+		f.MaxConditions = n
+	}
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+)
+
+func TestNewFilterFromValues(t *testing.T) {
+	v, _ := url.ParseQuery("filter[name][like]=jo%25&filter[status]=1&filter[status]=2&filterMode=and&sort=-created_at&page=2&page_size=5&q=x")
+	f, err := NewFilterFromValues(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Sort != "-created_at" || f.Page != 2 || f.PageSize != 5 || f.Search != "x" {
+		t.Errorf("unexpected filter %+v", f)
+	}
+	if len(f.Filters) != 2 {
+		t.Errorf("want name and status conditions, got %v", f.Filters)
+	}
+}
+
+func TestNewFilterFromValuesMalformed(t *testing.T) {
+	for _, q := range []string{"filter[name=1", "filter[name][like][x]=1", "page=abc"} {
+		v, _ := url.ParseQuery(q)
+		if _, err := NewFilterFromValues(v); err == nil {
+			t.Errorf("%s: want error", q)
+		}
+	}
+}
+
+func TestNewFilterFromValuesMaxConditions(t *testing.T) {
+	v := url.Values{}
+	for i := 0; i < defaultMaxConditions+1; i++ {
+		v.Set(fmt.Sprintf("filter[f%d]", i), "1")
+	}
+	if _, err := NewFilterFromValues(v); err == nil {
+		t.Fatal("want error above the default limit")
+	}
+	if _, err := NewFilterFromValues(v, WithMaxConditions(-1)); err != nil {
+		t.Errorf("unlimited: %v", err)
+	}
+	small := url.Values{"filter[name][gte]": {"a"}, "filter[name][lte]": {"b"}, "filter[status]": {"1"}}
+	if _, err := NewFilterFromValues(small, WithMaxConditions(2)); err == nil {
+		t.Error("want error when operators exceed the limit")
+	}
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// ModelFieldsOption FilterableFromModel 的配置项
+type ModelFieldsOption func(*modelFieldsOptions)
+
+type modelFieldsOptions struct {
+	namer     schema.Namer
+	qualified bool
+}
+
+// WithNamer 指定解析列名使用的命名策略，需与 gorm.Config.NamingStrategy 一致，默认 schema.NamingStrategy{}
+func WithNamer(namer schema.Namer) ModelFieldsOption {
+	return func(o *modelFieldsOptions) {
+		o.namer = namer
+	}
+}
+
+// WithQualifiedColumns 返回带表名前缀的列名，如 "users.name"，用于 JOIN 查询
+func WithQualifiedColumns() ModelFieldsOption {
+	return func(o *modelFieldsOptions) {
+		o.qualified = true
+	}
+}
+
+// FilterableFromModel 根据模型 T 的 gorm 结构返回可筛选和可排序的列。
+// 字段标签 filter:"-" 表示排除该列（如 password_hash），filter:"sortable" 表示同时加入可排序列
+func FilterableFromModel[T any](opts ...ModelFieldsOption) (filterable []string, sortable []string, err error) {
+	o := modelFieldsOptions{namer: schema.NamingStrategy{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	// schema 缓存不区分命名策略，每次使用独立的缓存
+	sch, err := schema.Parse(new(T), &sync.Map{}, o.namer)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, field := range sch.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		tag := field.Tag.Get("filter")
+		if tag == "-" {
+			continue
+		}
+		name := field.DBName
+		if o.qualified {
+			name = sch.Table + "." + name
+		}
+		filterable = append(filterable, name)
+		if tag == "sortable" {
+			sortable = append(sortable, name)
+		}
+	}
+	return filterable, sortable, nil
+}
+
+// 模型白名单缓存，key 为模型类型和命名策略，不同命名策略得到的列名不同
+var modelWhitelistCache sync.Map
+
+type modelWhitelistKey struct {
+	model reflect.Type
+	namer schema.Namer
+}
+
+type modelWhitelist struct {
+	filterable []string
+	sortable   []string
+}
+
+// 按模型类型和命名策略缓存 FilterableFromModel 的结果，命名策略不可比较时不缓存
+func cachedModelWhitelist[T any](namer schema.Namer) (modelWhitelist, error) {
+	key := modelWhitelistKey{model: reflect.TypeOf((*T)(nil)).Elem(), namer: namer}
+	cacheable := namer != nil && reflect.ValueOf(namer).Comparable()
+	if cacheable {
+		if v, ok := modelWhitelistCache.Load(key); ok {
+			return v.(modelWhitelist), nil
+		}
+	}
+	filterable, sortable, err := FilterableFromModel[T](WithNamer(namer))
+	if err != nil {
+		return modelWhitelist{}, err
+	}
+	wl := modelWhitelist{filterable: filterable, sortable: sortable}
+	if cacheable {
+		modelWhitelistCache.Store(key, wl)
+	}
+	return wl, nil
+}
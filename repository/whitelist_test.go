@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+type account struct {
+	ID           uint
+	UserName     string `filter:"sortable"`
+	PasswordHash string `filter:"-"`
+}
+
+func TestFilterableFromModel(t *testing.T) {
+	filterable, sortable, err := FilterableFromModel[account]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(filterable); got != 2 || filterable[0] != "id" || filterable[1] != "user_name" {
+		t.Errorf("filterable = %v", filterable)
+	}
+	if len(sortable) != 1 || sortable[0] != "user_name" {
+		t.Errorf("sortable = %v", sortable)
+	}
+}
+
+func TestCachedModelWhitelistPerNamer(t *testing.T) {
+	plain, err := cachedModelWhitelist[account](schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upper, err := cachedModelWhitelist[account](schema.NamingStrategy{NoLowerCase: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.sortable[0] != "user_name" || upper.sortable[0] != "UserName" {
+		t.Errorf("whitelists shared across naming strategies: %v, %v", plain.sortable, upper.sortable)
+	}
+}